@@ -2,14 +2,20 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"image/color"
+	"io"
 	"log"
+	"math"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
+	"taiyoukei/physics"
 	pb "taiyoukei/proto"
+	"taiyoukei/units"
 
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
@@ -23,30 +29,123 @@ const PLOT_FREQUENCY = 1000
 const CENTER = "E"
 const BODY = "M"
 
+type argParser struct {
+	server     string
+	parent     string
+	names      string
+	decimation uint64
+	frame      string
+	buffer     uint
+	lossless   bool
+	backfill   bool
+}
+
+func (parser *argParser) parse() {
+	flag.StringVar(&parser.server, "server", ":50051", "The server address in the format of host:port")
+	flag.StringVar(&parser.parent, "parent", "S", "Body the osculating orbital elements of the others are computed around")
+	flag.StringVar(&parser.names, "names", "", "Comma-separated bodies to capture, every body when empty")
+	flag.Uint64Var(&parser.decimation, "decimation", 1, "Only capture the rounds that are a multiple of it")
+	flag.StringVar(&parser.frame, "frame", "", "Body the positions are captured relative to, or \"barycenter\"; the inertial frame when empty")
+	flag.UintVar(&parser.buffer, "buffer", 64, "Snapshots the server queues for the capture")
+	flag.BoolVar(&parser.lossless, "lossless", true, "Have the server wait for the capture rather than drop snapshots when the queue is full")
+	flag.BoolVar(&parser.backfill, "backfill", false, "Start with the past rounds kept by the server when joining a running simulation")
+	flag.Parse()
+}
+
+func (parser *argParser) request() *pb.CelestialBodiesPositionRequest {
+	req := pb.CelestialBodiesPositionRequest{
+		Decimation: parser.decimation,
+		Frame:      parser.frame,
+		Buffer:     uint32(parser.buffer),
+		Lossless:   parser.lossless,
+	}
+	if parser.names != "" {
+		req.Names = strings.Split(parser.names, ",")
+	}
+	return &req
+}
+
 type Position struct {
+	T float64
 	X float64
 	Y float64
+	Z float64
+}
+
+// Osculating orbital elements of a body around the parent at a given time
+type Elements struct {
+	T      float64
+	A      float64 // Semi-major axis
+	E      float64 // Eccentricity
+	Omega  float64 // Argument of periapsis (longitude of periapsis for planar orbits)
+	Period float64 // NaN when the body is not bound to the parent
+}
+
+// Computes the elements of the two-body orbit matching the relative state of body around
+// parent at that instant
+func computeElements(time float64, body *pb.CelestialBody, parent *pb.CelestialBody, G float64) Elements {
+	mu := G * (body.Mass + parent.Mass)
+	relative := physics.Vector{
+		X:  body.X - parent.X,
+		Y:  body.Y - parent.Y,
+		Z:  body.Z - parent.Z,
+		Vx: body.Vx - parent.Vx,
+		Vy: body.Vy - parent.Vy,
+		Vz: body.Vz - parent.Vz,
+	}
+	elements := physics.Osculating(relative, mu)
+	return Elements{T: time, A: elements.A, E: elements.E, Omega: elements.Omega, Period: elements.Period(mu)}
+}
+
+func saveElementsToFile(name string, elements Elements) {
+	fileName := fmt.Sprintf("%s/%s_elements.txt", DATA_DIR, name)
+	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Fatalf("error opening file: %v", err)
+	}
+
+	_, err = file.WriteString(fmt.Sprintf("time:%v a:%v e:%v omega:%v period:%v\n", elements.T, elements.A, elements.E, elements.Omega, elements.Period))
+	if err != nil {
+		log.Fatalf("error writing to file: %v", err)
+	}
+	file.Close()
 }
 
-func saveToFile(name string, datum *pb.CelestialBody) {
+func saveToFile(name string, time float64, datum *pb.CelestialBody) {
 	fileName := fmt.Sprintf("%s/%s_data.txt", DATA_DIR, name)
 	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		log.Fatalf("error opening file: %v", err)
 	}
 
-	_, err = file.WriteString(fmt.Sprintf("%v\n", datum))
+	_, err = file.WriteString(fmt.Sprintf("time:%v %v\n", time, datum))
 	if err != nil {
 		log.Fatalf("error writing to file: %v", err)
 	}
 	file.Close()
 }
 
-func plotData(positionData map[string][]Position) {
+// Axis label with the unit of the quantity in the simulation's unit system
+func label(quantity string, unit string) string {
+	return fmt.Sprintf("%s [%s]", quantity, unit)
+}
+
+func latestTime(positionData map[string][]Position) float64 {
+	time := 0.
+	for _, positions := range positionData {
+		if len(positions) > 0 {
+			time = math.Max(time, positions[len(positions)-1].T)
+		}
+	}
+	return time
+}
+
+// Orbits are plotted as their projection onto the x-y plane
+func plotData(positionData map[string][]Position, system units.System) {
 	p := plot.New()
-	p.Title.Text = "Celestial Body Positions"
-	p.X.Label.Text = "X"
-	p.Y.Label.Text = "Y"
+	p.Title.Text = fmt.Sprintf("Celestial Body Positions at t = %.6g %s", latestTime(positionData), system.Time)
+	p.X.Label.Text = label("X", system.Length)
+	p.Y.Label.Text = label("Y", system.Length)
 
 	colorIndex := 0
 	colors := []color.Color{
@@ -79,11 +178,27 @@ func plotData(positionData map[string][]Position) {
 	}
 }
 
-func plotDataCenteredOn(positionData map[string][]Position, center string, body string) {
+// Positions of body relative to center at the times both were recorded, as a body joining
+// late or leaving early has fewer samples
+func relativePositions(body []Position, center []Position) []Position {
+	centerAt := make(map[float64]Position, len(center))
+	for _, pos := range center {
+		centerAt[pos.T] = pos
+	}
+	relative := make([]Position, 0, len(body))
+	for _, pos := range body {
+		if c, ok := centerAt[pos.T]; ok {
+			relative = append(relative, Position{T: pos.T, X: pos.X - c.X, Y: pos.Y - c.Y, Z: pos.Z - c.Z})
+		}
+	}
+	return relative
+}
+
+func plotDataCenteredOn(positionData map[string][]Position, center string, body string, system units.System) {
 	p := plot.New()
-	p.Title.Text = fmt.Sprintf("Relative position of %s to %s", body, center)
-	p.X.Label.Text = "X"
-	p.Y.Label.Text = "Y"
+	p.Title.Text = fmt.Sprintf("Relative position of %s to %s at t = %.6g %s", body, center, latestTime(positionData), system.Time)
+	p.X.Label.Text = label("X", system.Length)
+	p.Y.Label.Text = label("Y", system.Length)
 
 	colorIndex := 0
 	colors := []color.Color{
@@ -106,10 +221,11 @@ func plotDataCenteredOn(positionData map[string][]Position, center string, body
 		return
 	}
 
-	pts := make(plotter.XYs, len(bodyPosition))
-	for i, pos := range bodyPosition {
-		pts[i].X = pos.X - centerPosition[i].X
-		pts[i].Y = pos.Y - centerPosition[i].Y
+	relative := relativePositions(bodyPosition, centerPosition)
+	pts := make(plotter.XYs, len(relative))
+	for i, pos := range relative {
+		pts[i].X = pos.X
+		pts[i].Y = pos.Y
 	}
 
 	line, err := plotter.NewLine(pts)
@@ -128,15 +244,147 @@ func plotDataCenteredOn(positionData map[string][]Position, center string, body
 	}
 }
 
-func light_grpc_client() {
-	conn, err := grpc.Dial(":50051", grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+func plotDistanceOverTime(positionData map[string][]Position, center string, body string, system units.System) {
+	p := plot.New()
+	p.Title.Text = fmt.Sprintf("Distance of %s to %s over time", body, center)
+	p.X.Label.Text = label("Time", system.Time)
+	p.Y.Label.Text = label("Distance", system.Length)
+
+	bodyPosition, bodyExists := positionData[body]
+	centerPosition, centerExists := positionData[center]
+
+	if !bodyExists || !centerExists || len(bodyPosition) == 0 || len(centerPosition) == 0 {
+		log.Printf("No data available for %s or %s", body, center)
+		return
+	}
+
+	relative := relativePositions(bodyPosition, centerPosition)
+	pts := make(plotter.XYs, len(relative))
+	for i, pos := range relative {
+		pts[i].X = pos.T
+		pts[i].Y = math.Sqrt(pos.X*pos.X + pos.Y*pos.Y + pos.Z*pos.Z)
+	}
+
+	line, err := plotter.NewLine(pts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	line.LineStyle.Width = vg.Points(2)
+	line.Color = color.RGBA{R: 89, G: 114, B: 191, A: 255}
+
+	p.Add(line)
+	p.Legend.Add(body, line)
+
+	if err := p.Save(10*vg.Inch, 5*vg.Inch, fmt.Sprintf("data/distance_%s_to_%s.png", body, center)); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// One plot per element showing its evolution for every body, to spot precession and
+// numerical drift
+func plotElements(elementsData map[string][]Elements, parent string, system units.System) {
+	colors := []color.Color{
+		color.RGBA{R: 89, G: 114, B: 191, A: 255},
+		color.RGBA{R: 89, G: 191, B: 114, A: 255},
+		color.RGBA{R: 191, G: 114, B: 89, A: 255},
+	}
+	for _, element := range []struct {
+		name  string
+		label string
+		value func(Elements) float64
+	}{
+		{"a", label("Semi-major axis", system.Length), func(e Elements) float64 { return e.A }},
+		{"e", "Eccentricity", func(e Elements) float64 { return e.E }},
+		{"omega", label("Argument of periapsis", "rad"), func(e Elements) float64 { return e.Omega }},
+		{"period", label("Period", system.Time), func(e Elements) float64 { return e.Period }},
+	} {
+		p := plot.New()
+		p.Title.Text = fmt.Sprintf("%s around %s over time", element.label, parent)
+		p.X.Label.Text = label("Time", system.Time)
+		p.Y.Label.Text = element.label
+
+		colorIndex := 0
+		for name, elements := range elementsData {
+			pts := make(plotter.XYs, 0, len(elements))
+			for _, e := range elements {
+				if value := element.value(e); !math.IsNaN(value) && !math.IsInf(value, 0) {
+					pts = append(pts, plotter.XY{X: e.T, Y: value})
+				}
+			}
+			if len(pts) == 0 {
+				continue
+			}
+			line, err := plotter.NewLine(pts)
+			if err != nil {
+				log.Fatal(err)
+			}
+			line.LineStyle.Width = vg.Points(2)
+			line.Color = colors[colorIndex%len(colors)]
+			colorIndex++
+
+			p.Add(line)
+			p.Legend.Add(name, line)
+		}
+
+		if err := p.Save(10*vg.Inch, 5*vg.Inch, fmt.Sprintf("data/elements_%s.png", element.name)); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// Saves the states of a snapshot, and the elements of the other bodies when the parent is
+// part of it
+func record(update *pb.Data, parent string, system units.System, positionData map[string][]Position, elementsData map[string][]Elements) {
+	for name, datum := range update.Content {
+		saveToFile(name, update.Time, datum)
+		positionData[name] = append(positionData[name], Position{T: update.Time, X: datum.X, Y: datum.Y, Z: datum.Z})
+		log.Printf("name = %v datum = %v", name, datum)
+	}
+	if parentDatum, ok := update.Content[parent]; ok {
+		for name, datum := range update.Content {
+			if name == parent {
+				continue
+			}
+			elements := computeElements(update.Time, datum, parentDatum, system.G)
+			saveElementsToFile(name, elements)
+			elementsData[name] = append(elementsData[name], elements)
+		}
+	}
+}
+
+// Rounds kept by the server before the first one received from the subscription, with
+// the same filters
+func backfill(client pb.CelestialServiceClient, req *pb.CelestialBodiesPositionRequest, first uint64) ([]*pb.Data, error) {
+	rounds := make([]*pb.Data, 0)
+	if first == 0 {
+		return rounds, nil
+	}
+	stream, err := client.History(context.Background(), &pb.HistoryRequest{Names: req.Names, ToRound: first - 1, Frame: req.Frame})
+	if err != nil {
+		return nil, err
+	}
+	for {
+		update, err := stream.Recv()
+		if err == io.EOF {
+			return rounds, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if update.Round < first && (req.Decimation == 0 || update.Round%req.Decimation == 0) {
+			rounds = append(rounds, update)
+		}
+	}
+}
+
+func light_grpc_client(server string, parent string, req *pb.CelestialBodiesPositionRequest, fill bool) {
+	conn, err := grpc.Dial(server, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
 	defer conn.Close()
 	client := pb.NewCelestialServiceClient(conn)
 
-	stream, err := client.CelestialBodiesPositions(context.Background(), &pb.CelestialBodiesPositionRequest{})
+	stream, err := client.CelestialBodiesPositions(context.Background(), req)
 	if err != nil {
 		log.Fatalf("error on getting celestial bodies positions: %v", err)
 	}
@@ -147,32 +395,62 @@ func light_grpc_client() {
 	}
 
 	positionData := make(map[string][]Position)
+	elementsData := make(map[string][]Elements)
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
 	counter := 1
+	// Declared by the server in every broadcast
+	system := units.Of(pb.Units_CANONICAL)
+	// Snapshots the server dropped because the capture lagged behind
+	dropped := uint64(0)
+
+	flush := func() {
+		plotData(positionData, system)
+		plotDataCenteredOn(positionData, CENTER, BODY, system)
+		plotDistanceOverTime(positionData, CENTER, BODY, system)
+		plotElements(elementsData, parent, system)
+	}
 
 outer_loop:
 	for {
 		select {
 		case <-c:
-			plotData(positionData)
+			flush()
 			break outer_loop
 		default:
 			update, err := stream.Recv()
 			if err != nil {
+				// The server is gone without a shutdown message, e.g. killed
 				log.Printf("error on receiving update: %v", err)
-				break
+				flush()
+				break outer_loop
+			}
+			system = units.Of(update.Units)
+			if update.Shutdown {
+				log.Printf("Simulation shut down after round %v", update.Round)
+				flush()
+				break outer_loop
+			}
+			if update.Dropped > dropped {
+				log.Printf("Warning -- %v snapshots dropped by the server before round %v", update.Dropped-dropped, update.Round)
+				dropped = update.Dropped
 			}
-			for name, datum := range update.Content {
-				saveToFile(name, datum)
-				positionData[name] = append(positionData[name], Position{X: datum.X, Y: datum.Y})
-				log.Printf("name = %v datum = %v", name, datum)
+			if fill {
+				rounds, err := backfill(client, req, update.Round)
+				if err != nil {
+					log.Printf("could not backfill the rounds before %v: %v", update.Round, err)
+				}
+				log.Printf("Backfilling %v rounds", len(rounds))
+				for _, past := range rounds {
+					record(past, parent, system, positionData, elementsData)
+				}
+				fill = false
 			}
+			record(update, parent, system, positionData, elementsData)
 			if counter%PLOT_FREQUENCY == 0 {
-				plotData(positionData)
-				plotDataCenteredOn(positionData, CENTER, BODY)
+				flush()
 			}
 			counter++
 		}
@@ -180,5 +458,7 @@ outer_loop:
 }
 
 func main() {
-	light_grpc_client()
+	parser := argParser{}
+	parser.parse()
+	light_grpc_client(parser.server, parser.parent, parser.request(), parser.backfill)
 }
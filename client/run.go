@@ -3,10 +3,15 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
+	"io"
 	"log"
 	"math"
 
+	"taiyoukei/physics"
 	pb "taiyoukei/proto"
+	"taiyoukei/scenario"
+	"taiyoukei/units"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -16,13 +21,39 @@ import (
 // Argument parser
 
 type argParser struct {
-	server string
-	name   string
-	mass   float64
-	x      float64
-	y      float64
-	vx     float64
-	vy     float64
+	server        string
+	name          string
+	mass          float64
+	x             float64
+	y             float64
+	z             float64
+	vx            float64
+	vy            float64
+	vz            float64
+	solver        string
+	dt            float64
+	tolerance     float64
+	join          uint64
+	leave         uint64
+	radius        float64
+	parent        string
+	a             float64
+	e             float64
+	omega         float64
+	meanAnomaly   float64
+	inclination   float64
+	node          float64
+	units         string
+	force         string
+	softening     float64
+	theta         float64
+	primary       string
+	j2            float64
+	primaryRadius float64
+	testParticle  bool
+	particles     string
+	group         string
+	thrust        string
 }
 
 func (parser *argParser) parse() {
@@ -31,9 +62,38 @@ func (parser *argParser) parse() {
 	flag.Float64Var(&parser.mass, "mass", 1, "Mass of the celestial body")
 	flag.Float64Var(&parser.x, "x", 1, "Initial x-position")
 	flag.Float64Var(&parser.y, "y", 1, "Initial y-position")
+	flag.Float64Var(&parser.z, "z", 0, "Initial z-position")
 	flag.Float64Var(&parser.vx, "vx", 1, "Initial x-speed")
 	flag.Float64Var(&parser.vy, "vy", 1, "Initial y-speed")
+	flag.Float64Var(&parser.vz, "vz", 0, "Initial z-speed")
+	flag.StringVar(&parser.solver, "solver", physics.RK4, "Integrator to use: rk4, leapfrog, yoshida4, forest-ruth or dopri5 (adaptive)")
+	flag.Float64Var(&parser.dt, "dt", 0.00001, "Step size proposed to the server (initial proposal for adaptive solvers)")
+	flag.Float64Var(&parser.tolerance, "tolerance", 1e-9, "Local error tolerance of adaptive solvers")
+	flag.Uint64Var(&parser.join, "join", 0, "Round at which the body enters a running simulation")
+	flag.Uint64Var(&parser.leave, "leave", 0, "Round after which the body leaves the simulation (0 to stay until the end)")
+	flag.Float64Var(&parser.radius, "radius", 0, "Radius of the celestial body for collisions (0 for a point mass)")
+	flag.StringVar(&parser.parent, "parent", "", "Parent body of the orbital elements: when set, the initial state is computed from the elements instead of -x -y -z -vx -vy -vz")
+	flag.Float64Var(&parser.a, "a", 1, "Semi-major axis around the parent")
+	flag.Float64Var(&parser.e, "e", 0, "Eccentricity around the parent")
+	flag.Float64Var(&parser.omega, "omega", 0, "Argument of periapsis around the parent (radians)")
+	flag.Float64Var(&parser.meanAnomaly, "M", 0, "Mean anomaly around the parent (radians)")
+	flag.Float64Var(&parser.inclination, "inc", 0, "Inclination on the x-y plane (radians)")
+	flag.Float64Var(&parser.node, "node", 0, "Longitude of the ascending node (radians)")
+	flag.StringVar(&parser.units, "units", "canonical", "Unit system of the initial conditions: canonical (G=1), si or astronomical (AU, solar mass, day)")
+	flag.StringVar(&parser.force, "force", physics.NEWTON, "Comma separated force models: newton, 1pn (relativistic correction of the primary) and j2 (oblateness of the primary)")
+	flag.Float64Var(&parser.softening, "softening", 0, "Plummer softening length of the Newtonian attraction")
+	flag.Float64Var(&parser.theta, "theta", 0, "Opening angle of the Barnes-Hut approximation of the Newtonian attraction (0 for the exact sum)")
+	flag.StringVar(&parser.primary, "primary", "", "Body the 1pn and j2 forces are computed around (defaults to -parent)")
+	flag.Float64Var(&parser.j2, "j2", 0, "J2 zonal harmonic of the primary")
+	flag.Float64Var(&parser.primaryRadius, "primary-radius", 0, "Equatorial radius of the primary for the j2 force")
+	flag.BoolVar(&parser.testParticle, "test-particle", false, "The body is integrated in the field of the others but exerts no force")
+	flag.StringVar(&parser.particles, "particles", "", "JSON file listing test particles integrated along with the body, with their name, mass, x, y, z, vx, vy, vz and radius")
+	flag.StringVar(&parser.group, "group", "", "JSON file listing further bodies integrated along with the body, in the format of -particles plus an optional test_particle flag")
+	flag.StringVar(&parser.thrust, "thrust", "", "JSON file listing the thrust segments of the body, with their start, stop, direction (prograde or radial around -primary, or fixed along x, y, z), acceleration and mass_flow")
 	flag.Parse()
+	if parser.primary == "" {
+		parser.primary = parser.parent
+	}
 }
 
 // -------------------------------------------------------------------------------------
@@ -42,13 +102,26 @@ func (parser *argParser) parse() {
 // Light weight struct to carry around the fundalental necessary data
 
 type lightCelestialBody struct {
-	sequence uint64
-	name     string
-	mass     float64
-	x        float64
-	y        float64
-	vx       float64
-	vy       float64
+	sequence  uint64
+	name      string
+	mass      float64
+	x         float64
+	y         float64
+	z         float64
+	vx        float64
+	vy        float64
+	vz        float64
+	dt        float64
+	joinRound uint64
+	radius    float64
+	solver    string
+	// Test particles are integrated in the field of the other bodies but exert no force
+	testParticle bool
+	thrusting    bool // Accelerated by its thrust over the last round
+}
+
+func (body *lightCelestialBody) state() physics.Vector {
+	return physics.Vector{X: body.x, Y: body.y, Z: body.z, Vx: body.vx, Vy: body.vy, Vz: body.vz}
 }
 
 // -------------------------------------------------------------------------------------
@@ -57,21 +130,26 @@ type lightCelestialBody struct {
 // Builder for a connection object
 
 type celestialConnectionBuilder struct {
-	server    string
-	solver    Solver
-	fh        celestialRateFunctionHandler
-	init_data lightCelestialBody
+	server      string
+	solver      physics.Solver
+	fh          physics.RateFunctionHandler
+	init_data   lightCelestialBody
+	leave_round uint64
+	elements    *orbitalElements
+	units       units.System
+	group       []lightCelestialBody
+	thrust      *physics.ThrustForce
 }
 
 func (builder *celestialConnectionBuilder) set_server(server string) {
 	builder.server = server
 }
 
-func (builder *celestialConnectionBuilder) set_solver(solver Solver) {
+func (builder *celestialConnectionBuilder) set_solver(solver physics.Solver) {
 	builder.solver = solver
 }
 
-func (builder *celestialConnectionBuilder) set_rateFunctionHandler(fh celestialRateFunctionHandler) {
+func (builder *celestialConnectionBuilder) set_rateFunctionHandler(fh physics.RateFunctionHandler) {
 	builder.fh = fh
 }
 
@@ -79,6 +157,27 @@ func (builder *celestialConnectionBuilder) set_initial_data(init_data lightCeles
 	builder.init_data = init_data
 }
 
+func (builder *celestialConnectionBuilder) set_leave_round(leave_round uint64) {
+	builder.leave_round = leave_round
+}
+
+func (builder *celestialConnectionBuilder) set_units(system units.System) {
+	builder.units = system
+	builder.fh.G = system.G
+}
+
+func (builder *celestialConnectionBuilder) set_group(group []lightCelestialBody) {
+	builder.group = group
+}
+
+func (builder *celestialConnectionBuilder) set_thrust(thrust *physics.ThrustForce) {
+	builder.thrust = thrust
+}
+
+func (builder *celestialConnectionBuilder) set_orbital_elements(elements *orbitalElements) {
+	builder.elements = elements
+}
+
 func (builder *celestialConnectionBuilder) build() (celestialConnection, error) {
 	conn, err := grpc.Dial(builder.server, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
 	if err != nil {
@@ -94,11 +193,16 @@ func (builder *celestialConnectionBuilder) build() (celestialConnection, error)
 		return celestialConnection{}, err
 	}
 	c := celestialConnection{
-		conn:   conn,
-		stream: stream,
-		data:   builder.init_data,
-		solver: builder.solver,
-		fh:     builder.fh,
+		conn:       conn,
+		stream:     stream,
+		data:       builder.init_data,
+		solver:     builder.solver,
+		fh:         builder.fh,
+		leaveRound: builder.leave_round,
+		elements:   builder.elements,
+		units:      builder.units,
+		group:      builder.group,
+		thrust:     builder.thrust,
 	}
 	return c, nil
 }
@@ -109,30 +213,58 @@ func (builder *celestialConnectionBuilder) build() (celestialConnection, error)
 // Connection object
 
 type celestialConnection struct {
-	conn   *grpc.ClientConn
-	stream pb.CelestialService_CelestialUpdateClient
-	data   lightCelestialBody
-	solver Solver
-	fh     celestialRateFunctionHandler
+	conn       *grpc.ClientConn
+	stream     pb.CelestialService_CelestialUpdateClient
+	data       lightCelestialBody
+	solver     physics.Solver
+	fh         physics.RateFunctionHandler
+	leaveRound uint64
+	elements   *orbitalElements // Pending until resolved against the first broadcast
+	units      units.System
+	group      []lightCelestialBody // Bodies integrated along with the body
+	thrust     *physics.ThrustForce // Only accelerates the body, not its group
+}
+
+func (c *celestialConnection) message(body lightCelestialBody) *pb.CelestialBody {
+	return &pb.CelestialBody{
+		Sequence:     body.sequence,
+		Name:         body.name,
+		Mass:         body.mass,
+		X:            body.x,
+		Y:            body.y,
+		Z:            body.z,
+		Vx:           body.vx,
+		Vy:           body.vy,
+		Vz:           body.vz,
+		Dt:           body.dt,
+		JoinRound:    body.joinRound,
+		Radius:       body.radius,
+		Solver:       body.solver,
+		Units:        c.units.Units,
+		TestParticle: body.testParticle,
+		Thrusting:    body.thrusting,
+	}
 }
 
 func (c *celestialConnection) sendUpdate() error {
-	data := pb.CelestialBody{
-		Sequence: c.data.sequence,
-		Name:     c.data.name,
-		Mass:     c.data.mass,
-		X:        c.data.x,
-		Y:        c.data.y,
-		Vx:       c.data.vx,
-		Vy:       c.data.vy,
+	// Until its state is known, a body defined by orbital elements is a massless
+	// placeholder that does not disturb the others
+	self := c.data
+	if c.elements != nil {
+		self.mass = 0
+		self.radius = 0
+	}
+	data := c.message(self)
+	for _, member := range c.group {
+		data.Group = append(data.Group, c.message(member))
 	}
 
-	err := c.stream.Send(&data)
+	err := c.stream.Send(data)
 	if err != nil {
 		log.Printf("could not send update: %v", err)
 		return err
 	}
-	log.Printf("Sending update %v\n", &data)
+	log.Printf("Sending update %v\n", data)
 	return nil
 }
 
@@ -140,50 +272,96 @@ func (c *celestialConnection) closeConnection() {
 	c.conn.Close()
 }
 
-func (c *celestialConnection) parseBroadcastData(broadcast *pb.Data) (self lightCelestialBody, others []lightCelestialBody) {
-	self = lightCelestialBody{}
-	others = make([]lightCelestialBody, 0)
-	for name, body := range broadcast.Content {
-		if name == c.data.name {
-			self.sequence = body.Sequence
-			if self.sequence != c.data.sequence {
-				log.Printf("Warning -- sequence is not respected -- expected sequence number: %v -- received sequence number: %v", c.data.sequence, self.sequence)
-			}
-			self.name = body.Name
-			self.mass = body.Mass
-			self.x = body.X
-			self.y = body.Y
-			self.vx = body.Vx
-			self.vy = body.Vy
-		} else {
-			others = append(
-				others,
-				lightCelestialBody{
-					sequence: body.Sequence,
-					name:     body.Name,
-					mass:     body.Mass,
-					x:        body.X,
-					y:        body.Y,
-					vx:       body.Vx,
-					vy:       body.Vy,
-				},
-			)
+func vectorOf(body *pb.CelestialBody) physics.Vector {
+	return physics.Vector{X: body.X, Y: body.Y, Z: body.Z, Vx: body.Vx, Vy: body.Vy, Vz: body.Vz}
+}
+
+// Advances one of our bodies over the round from its state in the broadcast, which is
+// authoritative, e.g. after the server restored a checkpoint
+func (c *celestialConnection) integrate(body lightCelestialBody, datum *pb.CelestialBody, dt float64) lightCelestialBody {
+	if datum.Sequence != body.sequence {
+		log.Printf("Warning -- sequence is not respected -- expected sequence number: %v -- received sequence number: %v", body.sequence, datum.Sequence)
+	}
+	if datum.Solver != body.solver {
+		log.Printf("Warning -- solver %v differs from the checkpointed solver %v", body.solver, datum.Solver)
+	}
+	sigma := vectorOf(datum)
+	log.Printf("%v sigma = %v", body.name, sigma)
+	fh := c.fh.For(body.name)
+	if c.thrust != nil && body.name == c.data.name {
+		fh.Forces = append(append([]physics.ForceModel{}, fh.Forces...), c.thrust)
+	}
+	sigma = c.solver.Step(sigma, fh.Evaluate, dt)
+	// Mass and radius change when the body absorbs another one
+	body.mass = datum.Mass
+	body.radius = datum.Radius
+	body.sequence = datum.Sequence + 1
+	body.x = sigma.X
+	body.y = sigma.Y
+	body.z = sigma.Z
+	body.vx = sigma.Vx
+	body.vy = sigma.Vy
+	body.vz = sigma.Vz
+	if adaptive, ok := c.solver.(physics.AdaptiveSolver); ok {
+		body.dt = adaptive.ProposeStep(dt)
+		log.Printf("Proposing step %v for %v", body.dt, body.name)
+	}
+	return body
+}
+
+// Impulses of the round change the velocities of the broadcast before it is integrated,
+// whichever client integrates the body they apply to
+func applyImpulses(broadcast *pb.Data) {
+	for _, impulse := range broadcast.Impulses {
+		body, ok := broadcast.Content[impulse.Name]
+		if !ok {
+			continue
 		}
+		body.Vx += impulse.Dvx
+		body.Vy += impulse.Dvy
+		body.Vz += impulse.Dvz
+		log.Printf("Impulse (%v, %v, %v) applied to %v", impulse.Dvx, impulse.Dvy, impulse.Dvz, impulse.Name)
 	}
-	return
+}
+
+// The propellant burnt over the round is lost by the body
+func (c *celestialConnection) burn(dt float64) {
+	segment := c.thrust.Active()
+	c.data.thrusting = segment != nil
+	if segment == nil {
+		return
+	}
+	c.data.mass = math.Max(c.data.mass-segment.MassFlow*dt, 0)
+	log.Printf("Thrusting %v %v, mass %v", segment.Direction, segment.Acceleration, c.data.mass)
 }
 
 func (c *celestialConnection) udpateData(broadcast *pb.Data, dt float64) {
-	self, others := c.parseBroadcastData(broadcast)
-	c.fh.update(others)
-	sigma := hamiltonVector{x: self.x, y: self.y, vx: self.vx, vy: self.vy}
-	log.Printf("broadcast = %v sigma = %v", broadcast, sigma)
-	sigma = c.solver.step(sigma, c.fh.evaluate, dt)
-	c.data.sequence = c.data.sequence + 1
-	c.data.x = sigma.x
-	c.data.y = sigma.y
-	c.data.vx = sigma.vx
-	c.data.vy = sigma.vy
+	applyImpulses(broadcast)
+	// The handler leaves the integrated body out of the bodies of the broadcast
+	bodies := make([]physics.Body, 0, len(broadcast.Content))
+	for name, body := range broadcast.Content {
+		bodies = append(bodies, physics.Body{Name: name, Mass: body.Mass, State: vectorOf(body), TestParticle: body.TestParticle})
+	}
+	c.fh.Update(bodies)
+	log.Printf("broadcast = %v", broadcast)
+	if c.thrust != nil {
+		c.thrust.Time = broadcast.Time
+	}
+	c.data = c.integrate(c.data, broadcast.Content[c.data.name], dt)
+	if c.thrust != nil {
+		c.burn(dt)
+	}
+	// Bodies of the group missing from the broadcast were absorbed or removed by the server
+	group := make([]lightCelestialBody, 0, len(c.group))
+	for _, member := range c.group {
+		datum, ok := broadcast.Content[member.name]
+		if !ok {
+			log.Printf("%v is no longer part of the simulation", member.name)
+			continue
+		}
+		group = append(group, c.integrate(member, datum, dt))
+	}
+	c.group = group
 }
 
 func (c *celestialConnection) run() error {
@@ -203,11 +381,59 @@ func (c *celestialConnection) run() error {
 
 		log.Printf("Received broadcast update %v\n", broadcast)
 
-		// Increment values
-		c.udpateData(broadcast, 0.00001)
+		if broadcast.Shutdown {
+			log.Printf("Simulation shut down after round %v", broadcast.Round)
+			c.stream.CloseSend()
+			c.closeConnection()
+			return nil
+		}
+
+		if broadcast.Units != c.units.Units {
+			c.closeConnection()
+			err := fmt.Errorf("simulation uses %v units, not %v", units.Of(broadcast.Units).Name, c.units.Name)
+			log.Printf("Unit system mismatch: %v", err)
+			return err
+		}
+
+		for _, merger := range broadcast.Mergers {
+			if merger.Absorbed == c.data.name {
+				// The server removes the group along with the body
+				log.Printf("Absorbed by %v, leaving the simulation", merger.Survivor)
+				c.stream.CloseSend()
+				c.closeConnection()
+				return nil
+			}
+		}
+
+		if c.elements != nil {
+			if err := c.resolveOrbitalElements(broadcast); err != nil {
+				c.closeConnection()
+				log.Printf("Could not resolve orbital elements: %v", err)
+				return err
+			}
+		}
+
+		if c.leaveRound > 0 && broadcast.Round >= c.leaveRound {
+			log.Printf("Leaving the simulation at round %v", broadcast.Round)
+			c.stream.CloseSend()
+			c.closeConnection()
+			return nil
+		}
+
+		// Increment values with the step negotiated by the server, falling back on our own
+		// proposal for servers that do not negotiate one
+		dt := broadcast.Dt
+		if dt <= 0 {
+			dt = c.data.dt
+		}
+		c.udpateData(broadcast, dt)
 
-		// Send updated values
+		// Send updated values. The server ending the stream, e.g. on shutdown, shows as EOF
+		// here while the reason is left to receive
 		err = c.sendUpdate()
+		if err == io.EOF {
+			continue
+		}
 		if err != nil {
 			c.conn.Close()
 			log.Fatalf("failed to send update: %v", err)
@@ -224,100 +450,69 @@ func (c *celestialConnection) run() error {
 // -------------------------------------------------------------------------------------
 
 // -------------------------------------------------------------------------------------
-// Vector for hamiltonian calculations
+// Orbital elements of a body around a parent body
 
-type hamiltonVector struct {
-	x  float64
-	y  float64
-	vx float64
-	vy float64
+type orbitalElements struct {
+	parent string
+	physics.OrbitalElements
 }
 
-func (sigma1 *hamiltonVector) Add(sigma2 hamiltonVector) hamiltonVector {
-	return hamiltonVector{
-		x:  sigma1.x + sigma2.x,
-		y:  sigma1.y + sigma2.y,
-		vx: sigma1.vx + sigma2.vx,
-		vy: sigma1.vy + sigma2.vy,
+// Replaces our placeholder in the broadcast with the state given by the orbital elements
+// around the current state of the parent
+func (c *celestialConnection) resolveOrbitalElements(broadcast *pb.Data) error {
+	parent, ok := broadcast.Content[c.elements.parent]
+	if !ok {
+		return fmt.Errorf("parent %v is not part of the simulation", c.elements.parent)
 	}
-}
-
-func (sigma1 *hamiltonVector) scalarMultiply(lambda float64) hamiltonVector {
-	return hamiltonVector{
-		x:  sigma1.x * lambda,
-		y:  sigma1.y * lambda,
-		vx: sigma1.vx * lambda,
-		vy: sigma1.vy * lambda,
+	self, ok := broadcast.Content[c.data.name]
+	if !ok {
+		return fmt.Errorf("%v is missing from the broadcast", c.data.name)
 	}
+	sigma := c.elements.ToCartesian(c.units.G * (parent.Mass + c.data.mass))
+	self.Mass = c.data.mass
+	self.Radius = c.data.radius
+	self.X = parent.X + sigma.X
+	self.Y = parent.Y + sigma.Y
+	self.Z = parent.Z + sigma.Z
+	self.Vx = parent.Vx + sigma.Vx
+	self.Vy = parent.Vy + sigma.Vy
+	self.Vz = parent.Vz + sigma.Vz
+	log.Printf("Resolved orbital elements around %v: %v", c.elements.parent, self)
+	c.elements = nil
+	return nil
 }
 
 // -------------------------------------------------------------------------------------
 
 // -------------------------------------------------------------------------------------
-// Function type to pass the ODE rate function as arument of solver
-
-type rateFunction func(hamiltonVector) hamiltonVector
-
-// -------------------------------------------------------------------------------------
-
-// -------------------------------------------------------------------------------------
-// Interface to abstract solver properties
-
-type Solver interface {
-	step(sigma hamiltonVector, f rateFunction, dt float64) hamiltonVector
-}
-
-// -------------------------------------------------------------------------------------
-
-// -------------------------------------------------------------------------------------
-// Order 4 Runge-Kutta solver
-
-type RungeKutta4Solver struct {
-}
-
-func (rks *RungeKutta4Solver) step(sigma hamiltonVector, f rateFunction, dt float64) hamiltonVector {
-	F1 := f(sigma)
-	F2 := f(sigma.Add(F1.scalarMultiply(dt / 2.0)))
-	F3 := f(sigma.Add(F2.scalarMultiply(dt / 2.0)))
-	F4 := f(sigma.Add(F3.scalarMultiply(dt)))
-	sum_F := F1.Add(F2.scalarMultiply(2.0))
-	sum_F = sum_F.Add(F3.scalarMultiply(2.0))
-	sum_F = sum_F.Add(F4)
-	return sigma.Add(sum_F.scalarMultiply(dt / 6.0))
-}
-
-type celestialRateFunctionHandler struct {
-	bodies []hamiltonVector
-	masses []float64
-}
-
-func (fh *celestialRateFunctionHandler) evaluate(sigmai hamiltonVector) hamiltonVector {
-	rate := hamiltonVector{
-		x:  sigmai.vx,
-		y:  sigmai.vy,
-		vx: 0.,
-		vy: 0.,
-	}
-	for j, sigmaj := range fh.bodies {
-		muj := fh.masses[j]
-		d_ij := math.Sqrt((sigmaj.x-sigmai.x)*(sigmaj.x-sigmai.x) + (sigmaj.y-sigmai.y)*(sigmaj.y-sigmai.y))
-		Fx := muj * (sigmaj.x - sigmai.x) / (d_ij * d_ij * d_ij)
-		Fy := muj * (sigmaj.y - sigmai.y) / (d_ij * d_ij * d_ij)
-		rate.vx += Fx
-		rate.vy += Fy
-		log.Printf("mu = %v d = %v, F = %v", muj, d_ij, math.Sqrt(Fx*Fx+Fy*Fy))
-	}
-	return rate
-}
+// Bodies sent along with the body
 
-func (fh *celestialRateFunctionHandler) update(bodies []lightCelestialBody) {
-	// Creating copies as the number of bodies may vary
-	fh.bodies = make([]hamiltonVector, 0)
-	fh.masses = make([]float64, 0)
-	for _, body := range bodies {
-		fh.bodies = append(fh.bodies, hamiltonVector{x: body.x, y: body.y, vx: body.vx, vy: body.vy})
-		fh.masses = append(fh.masses, body.mass)
+// The members share the solver, step proposal and join round of the body. Every entry of a
+// file of test particles is a test particle
+func loadGroup(path string, body lightCelestialBody, testParticles bool) ([]lightCelestialBody, error) {
+	entries, err := scenario.LoadGroup(path)
+	if err != nil {
+		return nil, err
+	}
+	group := make([]lightCelestialBody, 0, len(entries))
+	for i, entry := range entries {
+		if entry.Name == "" || entry.Name == body.name {
+			return nil, fmt.Errorf("entry %v of %v needs a name of its own", i, path)
+		}
+		member := body
+		member.name = entry.Name
+		member.mass = entry.Mass
+		member.x = entry.X
+		member.y = entry.Y
+		member.z = entry.Z
+		member.vx = entry.Vx
+		member.vy = entry.Vy
+		member.vz = entry.Vz
+		member.radius = entry.Radius
+		member.testParticle = testParticles || entry.TestParticle
+		group = append(group, member)
 	}
+	return group, nil
 }
 
 // -------------------------------------------------------------------------------------
@@ -332,25 +527,95 @@ func main() {
 	mass := parser.mass
 	x := parser.x
 	y := parser.y
+	z := parser.z
 	vx := parser.vx
 	vy := parser.vy
+	vz := parser.vz
 
-	solver := RungeKutta4Solver{}
-	fh := celestialRateFunctionHandler{}
+	solver, err := physics.NewSolver(parser.solver, parser.tolerance)
+	if err != nil {
+		log.Fatalf("could not select solver: %v", err)
+	}
+	system, err := units.Parse(parser.units)
+	if err != nil {
+		log.Fatalf("could not select unit system: %v", err)
+	}
+	forces, err := physics.NewForceModels(parser.force, physics.ForceOptions{
+		Softening:     parser.softening,
+		Theta:         parser.theta,
+		Primary:       parser.primary,
+		C:             system.C,
+		J2:            parser.j2,
+		PrimaryRadius: parser.primaryRadius,
+	})
+	if err != nil {
+		log.Fatalf("could not select force models: %v", err)
+	}
+	fh := physics.RateFunctionHandler{Forces: forces}
 
 	builder := celestialConnectionBuilder{}
 	builder.set_server(server)
-	builder.set_solver(&solver)
+	builder.set_solver(solver)
 	builder.set_rateFunctionHandler(fh)
-	builder.set_initial_data(lightCelestialBody{
-		sequence: 1,
-		name:     name,
-		mass:     mass,
-		x:        x,
-		y:        y,
-		vx:       vx,
-		vy:       vy,
-	})
+	builder.set_units(system)
+	init_data := lightCelestialBody{
+		sequence:     1,
+		name:         name,
+		mass:         mass,
+		x:            x,
+		y:            y,
+		z:            z,
+		vx:           vx,
+		vy:           vy,
+		vz:           vz,
+		dt:           parser.dt,
+		joinRound:    parser.join,
+		radius:       parser.radius,
+		solver:       parser.solver,
+		testParticle: parser.testParticle,
+	}
+	builder.set_initial_data(init_data)
+	builder.set_leave_round(parser.leave)
+	group := make([]lightCelestialBody, 0)
+	if parser.group != "" {
+		members, err := loadGroup(parser.group, init_data, false)
+		if err != nil {
+			log.Fatalf("could not load group: %v", err)
+		}
+		group = append(group, members...)
+	}
+	if parser.particles != "" {
+		particles, err := loadGroup(parser.particles, init_data, true)
+		if err != nil {
+			log.Fatalf("could not load test particles: %v", err)
+		}
+		group = append(group, particles...)
+	}
+	builder.set_group(group)
+	if parser.thrust != "" {
+		segments, err := scenario.LoadThrust(parser.thrust)
+		if err != nil {
+			log.Fatalf("could not load thrust schedule: %v", err)
+		}
+		builder.set_thrust(&physics.ThrustForce{Segments: segments, Primary: parser.primary})
+	}
+	if parser.parent != "" {
+		elements := orbitalElements{
+			parent: parser.parent,
+			OrbitalElements: physics.OrbitalElements{
+				A:           parser.a,
+				E:           parser.e,
+				Omega:       parser.omega,
+				MeanAnomaly: parser.meanAnomaly,
+				Inclination: parser.inclination,
+				Node:        parser.node,
+			},
+		}
+		if err := elements.Validate(); err != nil {
+			log.Fatalf("invalid orbital elements: %v", err)
+		}
+		builder.set_orbital_elements(&elements)
+	}
 	c, err := builder.build()
 	if err != nil {
 		log.Fatalf("could not build celestialConnection: %v", err)
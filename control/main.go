@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	pb "taiyoukei/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const PAUSE = "pause"
+const RESUME = "resume"
+const STEP = "step"
+const SHUTDOWN = "shutdown"
+const IMPULSE = "impulse"
+
+type argParser struct {
+	server  string
+	command string
+	n       uint64
+	impulse pb.Impulse
+}
+
+func (parser *argParser) parse() {
+	flag.StringVar(&parser.server, "server", ":50051", "The server address in the format of host:port")
+	flag.StringVar(&parser.command, "command", PAUSE, "Command sent to the server: pause, resume, step, shutdown or impulse")
+	flag.Uint64Var(&parser.n, "n", 1, "Number of rounds broadcast by the step command before pausing")
+	flag.StringVar(&parser.impulse.Name, "name", "", "Body the impulse command changes the velocity of")
+	flag.Float64Var(&parser.impulse.Dvx, "dvx", 0, "x-speed added by the impulse")
+	flag.Float64Var(&parser.impulse.Dvy, "dvy", 0, "y-speed added by the impulse")
+	flag.Float64Var(&parser.impulse.Dvz, "dvz", 0, "z-speed added by the impulse")
+	flag.Uint64Var(&parser.impulse.Sequence, "sequence", 0, "Sequence of the body the impulse is applied at (0 to use -time)")
+	flag.Float64Var(&parser.impulse.Time, "time", 0, "Simulation time the impulse is applied at, when no sequence is given (0 for the next round)")
+	flag.Parse()
+}
+
+func send(client pb.ControlServiceClient, command string, n uint64, impulse *pb.Impulse) (*pb.ControlReply, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	switch command {
+	case PAUSE:
+		return client.Pause(ctx, &pb.ControlRequest{})
+	case RESUME:
+		return client.Resume(ctx, &pb.ControlRequest{})
+	case STEP:
+		return client.StepN(ctx, &pb.StepRequest{N: n})
+	case SHUTDOWN:
+		return client.Shutdown(ctx, &pb.ControlRequest{})
+	case IMPULSE:
+		return client.ScheduleImpulse(ctx, impulse)
+	}
+	log.Fatalf("unknown command %v", command)
+	return nil, nil
+}
+
+func main() {
+	parser := argParser{}
+	parser.parse()
+
+	conn, err := grpc.Dial(parser.server, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("did not connect: %v", err)
+	}
+	defer conn.Close()
+
+	reply, err := send(pb.NewControlServiceClient(conn), parser.command, parser.n, &parser.impulse)
+	if err != nil {
+		log.Fatalf("%v failed: %v", parser.command, err)
+	}
+	log.Printf("%v: next round %v, paused %v, %v steps left, %v impulses scheduled", parser.command, reply.Round, reply.Paused, reply.Steps, reply.Impulses)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"taiyoukei/scenario"
+)
+
+type argParser struct {
+	scenario string
+	goBinary string
+	delay    time.Duration
+}
+
+func (parser *argParser) parse() {
+	flag.StringVar(&parser.scenario, "scenario", "scenarios/sun_earth_moon.json", "Scenario file describing the system")
+	flag.StringVar(&parser.goBinary, "go", "go", "Go binary used to run the server and the clients")
+	flag.DurationVar(&parser.delay, "delay", time.Second, "Time given to the server to start before spawning the clients")
+	flag.Parse()
+}
+
+// Runs a program of the repository with `go run`, sharing our output
+func start(goBinary string, program string, args []string) (*exec.Cmd, error) {
+	cmd := exec.Command(goBinary, append([]string{"run", program}, args...)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// Own process group so that the compiled program is stopped along with `go run`
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	log.Printf("Started %v %v (pid %v)", program, args, cmd.Process.Pid)
+	return cmd, nil
+}
+
+func stop(cmd *exec.Cmd) {
+	if cmd.ProcessState != nil {
+		return
+	}
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+}
+
+func main() {
+	parser := argParser{}
+	parser.parse()
+
+	s, err := scenario.Load(parser.scenario)
+	if err != nil {
+		log.Fatalf("could not load scenario: %v", err)
+	}
+
+	server, err := start(parser.goBinary, "server/run.go", s.ServerArgs())
+	if err != nil {
+		log.Fatalf("could not start server: %v", err)
+	}
+	time.Sleep(parser.delay)
+
+	clients := make([]*exec.Cmd, 0, len(s.Bodies))
+	for _, body := range s.Bodies {
+		if body.Server {
+			continue
+		}
+		client, err := start(parser.goBinary, "client/run.go", s.ClientArgs(body))
+		if err != nil {
+			log.Printf("could not start client %v: %v", body.Name, err)
+			continue
+		}
+		clients = append(clients, client)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Wait()
+	}()
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case <-c:
+		log.Printf("Stopping the system")
+		stop(server)
+		<-done
+	case err := <-done:
+		log.Printf("Server exited: %v", err)
+	}
+	for _, client := range clients {
+		stop(client)
+		client.Wait()
+	}
+}
@@ -0,0 +1,44 @@
+package physics
+
+import "math"
+
+// -------------------------------------------------------------------------------------
+// Conserved quantities
+//
+// Total energy, linear momentum and angular momentum of an isolated system are constant:
+// their drift over a run measures the integration error.
+
+type ConservedQuantities struct {
+	Energy               float64
+	Px, Py, Pz           float64 // Linear momentum
+	Lx, Ly, Lz           float64 // Angular momentum
+	MomentumScale        float64 // Sum of the momentum norms, as the total is usually close to 0
+	AngularMomentumScale float64 // Sum of the angular momentum norms
+}
+
+// Conserved computes the quantities of a set of bodies under Newtonian gravity
+func Conserved(bodies []Body, G float64) ConservedQuantities {
+	q := ConservedQuantities{}
+	for i, bi := range bodies {
+		si := bi.State
+		q.Energy += 0.5 * bi.Mass * (si.Vx*si.Vx + si.Vy*si.Vy + si.Vz*si.Vz)
+		for _, bj := range bodies[i+1:] {
+			sj := bj.State
+			dx, dy, dz := si.X-sj.X, si.Y-sj.Y, si.Z-sj.Z
+			q.Energy -= G * bi.Mass * bj.Mass / math.Sqrt(dx*dx+dy*dy+dz*dz)
+		}
+		px, py, pz := bi.Mass*si.Vx, bi.Mass*si.Vy, bi.Mass*si.Vz
+		lx, ly, lz := cross(si.X, si.Y, si.Z, px, py, pz)
+		q.Px += px
+		q.Py += py
+		q.Pz += pz
+		q.Lx += lx
+		q.Ly += ly
+		q.Lz += lz
+		q.MomentumScale += math.Sqrt(px*px + py*py + pz*pz)
+		q.AngularMomentumScale += math.Sqrt(lx*lx + ly*ly + lz*lz)
+	}
+	return q
+}
+
+// -------------------------------------------------------------------------------------
@@ -0,0 +1,19 @@
+// Package physics holds the integration of the bodies of the simulation, shared by the
+// client, the server and the capture, and usable from other tools:
+//
+//   - Vector is the state of a body, advanced over a step by a Solver (NewSolver selects
+//     one by name) under a RateFunction,
+//   - RateFunctionHandler sums the ForceModel accelerations (NewForceModels) exerted by
+//     the other bodies, given with Update,
+//   - ThrustForce is a ForceModel accelerating a spacecraft along a schedule of
+//     ThrustSegment,
+//   - OrbitalElements converts between Keplerian elements and Cartesian states,
+//   - Conserved computes the energy, momentum and angular momentum of a set of bodies.
+//
+// Integrating a body around the Sun over one step:
+//
+//	fh := physics.RateFunctionHandler{G: 1, Forces: []physics.ForceModel{&physics.NewtonianForce{}}}
+//	fh.Update([]physics.Body{{Name: "S", Mass: 1}})
+//	solver, _ := physics.NewSolver(physics.RK4, 0)
+//	earth := solver.Step(physics.Vector{X: 1, Vy: 1}, fh.Evaluate, 0.001)
+package physics
@@ -0,0 +1,154 @@
+package physics
+
+import (
+	"fmt"
+	"math"
+)
+
+// -------------------------------------------------------------------------------------
+// Orbital elements of a body around a parent body
+
+// Keplerian elements of the orbit of a body around a parent body, angles in radians
+type OrbitalElements struct {
+	A           float64 // Semi-major axis, negative for unbound orbits
+	E           float64 // Eccentricity
+	Omega       float64 // Argument of periapsis (longitude of periapsis for planar orbits)
+	MeanAnomaly float64
+	Inclination float64
+	Node        float64 // Longitude of the ascending node
+}
+
+// Validate checks that the elements describe an elliptic orbit
+func (elements *OrbitalElements) Validate() error {
+	if elements.A <= 0 {
+		return fmt.Errorf("semi-major axis must be positive, got %v", elements.A)
+	}
+	if elements.E < 0 || elements.E >= 1 {
+		return fmt.Errorf("only elliptic orbits are supported, got eccentricity %v", elements.E)
+	}
+	return nil
+}
+
+// EccentricAnomaly solves Kepler's equation M = E - e sin(E) with Newton's method
+func (elements *OrbitalElements) EccentricAnomaly() float64 {
+	E := elements.MeanAnomaly
+	if elements.E > 0.8 {
+		E = math.Pi
+	}
+	for i := 0; i < 50; i++ {
+		dE := (E - elements.E*math.Sin(E) - elements.MeanAnomaly) / (1 - elements.E*math.Cos(E))
+		E -= dE
+		if math.Abs(dE) < 1e-14 {
+			break
+		}
+	}
+	return E
+}
+
+// ToCartesian returns the state relative to the parent, mu being the gravitational parameter of the pair
+func (elements *OrbitalElements) ToCartesian(mu float64) Vector {
+	E := elements.EccentricAnomaly()
+	a := elements.A
+	e := elements.E
+	r := a * (1 - e*math.Cos(E))
+	b := math.Sqrt(1 - e*e)
+	// Position and speed in the orbital plane, periapsis along the first axis
+	px := a * (math.Cos(E) - e)
+	py := a * b * math.Sin(E)
+	pvx := -math.Sqrt(mu*a) / r * math.Sin(E)
+	pvy := math.Sqrt(mu*a) / r * b * math.Cos(E)
+	// Rotation by the argument of periapsis, the inclination and the ascending node
+	cosO, sinO := math.Cos(elements.Node), math.Sin(elements.Node)
+	cosw, sinw := math.Cos(elements.Omega), math.Sin(elements.Omega)
+	cosi, sini := math.Cos(elements.Inclination), math.Sin(elements.Inclination)
+	r11 := cosO*cosw - sinO*sinw*cosi
+	r12 := -cosO*sinw - sinO*cosw*cosi
+	r21 := sinO*cosw + cosO*sinw*cosi
+	r22 := -sinO*sinw + cosO*cosw*cosi
+	r31 := sinw * sini
+	r32 := cosw * sini
+	return Vector{
+		X:  r11*px + r12*py,
+		Y:  r21*px + r22*py,
+		Z:  r31*px + r32*py,
+		Vx: r11*pvx + r12*pvy,
+		Vy: r21*pvx + r22*pvy,
+		Vz: r31*pvx + r32*pvy,
+	}
+}
+
+// Period of the orbit, NaN when the body is not bound to the parent
+func (elements *OrbitalElements) Period(mu float64) float64 {
+	if elements.A <= 0 {
+		return math.NaN()
+	}
+	return 2 * math.Pi * math.Sqrt(elements.A*elements.A*elements.A/mu)
+}
+
+func cross(ax, ay, az, bx, by, bz float64) (float64, float64, float64) {
+	return ay*bz - az*by, az*bx - ax*bz, ax*by - ay*bx
+}
+
+func normalizeAngle(angle float64) float64 {
+	angle = math.Mod(angle, 2*math.Pi)
+	if angle < 0 {
+		angle += 2 * math.Pi
+	}
+	return angle
+}
+
+// Osculating computes the elements of the two-body orbit matching the state relative to
+// the parent at that instant, the inverse of ToCartesian. The ascending node is undefined
+// for orbits in the x-y plane and the periapsis for circular orbits, angles are then
+// measured from the x axis and from the node respectively.
+func Osculating(relative Vector, mu float64) OrbitalElements {
+	rx, ry, rz := relative.X, relative.Y, relative.Z
+	vx, vy, vz := relative.Vx, relative.Vy, relative.Vz
+	r := math.Sqrt(rx*rx + ry*ry + rz*rz)
+	v2 := vx*vx + vy*vy + vz*vz
+	hx, hy, hz := cross(rx, ry, rz, vx, vy, vz)
+	h := math.Sqrt(hx*hx + hy*hy + hz*hz)
+	// Eccentricity vector (v x h) / mu - r / |r|
+	ex, ey, ez := cross(vx, vy, vz, hx, hy, hz)
+	ex, ey, ez = ex/mu-rx/r, ey/mu-ry/r, ez/mu-rz/r
+	e := math.Sqrt(ex*ex + ey*ey + ez*ez)
+	elements := OrbitalElements{
+		A:           1 / (2/r - v2/mu),
+		E:           e,
+		Inclination: math.Acos(math.Max(-1, math.Min(1, hz/h))),
+	}
+
+	// Argument of latitude of the periapsis and of the body, measured in the orbital plane
+	// from the ascending node
+	var omega, latitude float64
+	nx, ny := -hy, hx
+	n := math.Sqrt(nx*nx + ny*ny)
+	if n > 1e-12*h {
+		elements.Node = normalizeAngle(math.Atan2(ny, nx))
+		sini := math.Sin(elements.Inclination)
+		omega = math.Atan2(ez/sini, (nx*ex+ny*ey)/n)
+		latitude = math.Atan2(rz/sini, (nx*rx+ny*ry)/n)
+	} else {
+		omega = math.Atan2(ey, ex)
+		latitude = math.Atan2(ry, rx)
+		if hz < 0 {
+			omega = -omega
+			latitude = -latitude
+		}
+	}
+	if e < 1e-12 {
+		omega = 0
+	}
+	elements.Omega = normalizeAngle(omega)
+
+	if elements.A > 0 {
+		trueAnomaly := latitude - omega
+		E := math.Atan2(math.Sqrt(1-e*e)*math.Sin(trueAnomaly), e+math.Cos(trueAnomaly))
+		elements.MeanAnomaly = normalizeAngle(E - e*math.Sin(E))
+	} else {
+		elements.MeanAnomaly = math.NaN()
+	}
+	return elements
+}
+
+// -------------------------------------------------------------------------------------
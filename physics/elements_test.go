@@ -0,0 +1,35 @@
+package physics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestOsculatingInvertsToCartesian(t *testing.T) {
+	for _, elements := range []OrbitalElements{
+		{A: 1, E: 0.0167, Omega: 1.8, MeanAnomaly: 0.3},
+		{A: 0.387, E: 0.2056, Omega: 0.5, MeanAnomaly: 4, Inclination: 0.12, Node: 0.84},
+		{A: 2.5, E: 0.7, Omega: 5.9, MeanAnomaly: 3.1, Inclination: 1.2, Node: 4.1},
+	} {
+		mu := 1.2
+		got := Osculating(elements.ToCartesian(mu), mu)
+		for _, pair := range [][2]float64{
+			{got.A, elements.A}, {got.E, elements.E}, {got.Omega, elements.Omega},
+			{got.MeanAnomaly, elements.MeanAnomaly}, {got.Inclination, elements.Inclination}, {got.Node, elements.Node},
+		} {
+			if math.Abs(pair[0]-pair[1]) > 1e-9 {
+				t.Fatalf("expected %+v, got %+v", elements, got)
+			}
+		}
+		if period := got.Period(mu); math.Abs(period-2*math.Pi*math.Sqrt(math.Pow(elements.A, 3)/mu)) > 1e-9 {
+			t.Fatalf("unexpected period %v", period)
+		}
+	}
+}
+
+func TestUnboundOrbitHasNoPeriod(t *testing.T) {
+	elements := Osculating(Vector{X: 1, Vy: 2}, 1)
+	if elements.A > 0 || !math.IsNaN(elements.Period(1)) {
+		t.Fatalf("expected an unbound orbit, got %+v", elements)
+	}
+}
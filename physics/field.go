@@ -0,0 +1,109 @@
+package physics
+
+import "sync"
+
+// -------------------------------------------------------------------------------------
+// Rate function handler for the gravitational interaction with the other bodies
+
+// Other body of the simulation acting on the integrated one
+type Body struct {
+	Name         string
+	Mass         float64
+	State        Vector
+	TestParticle bool // Exerts no force, whatever its mass
+}
+
+// State of the bodies at the start of the step, shared by the handlers of every body
+// integrated over that step
+type bodySet struct {
+	names  []string
+	bodies []Vector
+	masses []float64
+	index  map[string]int
+	once   sync.Once // Builds the tree on first use, from any handler sharing the set
+	tree   *octreeNode
+}
+
+// Rate function of a body under the force models, given the state of the other bodies at
+// the start of the step
+type RateFunctionHandler struct {
+	G      float64 // Gravitational constant of the unit system
+	Forces []ForceModel
+	others *bodySet
+	self   int // Index of the integrated body in others, -1 when it is not part of them
+}
+
+func (fh *RateFunctionHandler) Evaluate(sigmai Vector) Vector {
+	rate := Vector{
+		X:  sigmai.Vx,
+		Y:  sigmai.Vy,
+		Z:  sigmai.Vz,
+		Vx: 0.,
+		Vy: 0.,
+		Vz: 0.,
+	}
+	for _, force := range fh.Forces {
+		ax, ay, az := force.Acceleration(sigmai, fh)
+		rate.Vx += ax
+		rate.Vy += ay
+		rate.Vz += az
+	}
+	return rate
+}
+
+func (fh *RateFunctionHandler) Update(bodies []Body) {
+	// Creating copies as the number of bodies may vary
+	others := bodySet{
+		names:  make([]string, 0, len(bodies)),
+		bodies: make([]Vector, 0, len(bodies)),
+		masses: make([]float64, 0, len(bodies)),
+		index:  make(map[string]int, len(bodies)),
+	}
+	for _, body := range bodies {
+		if body.TestParticle {
+			continue
+		}
+		others.index[body.Name] = len(others.names)
+		others.names = append(others.names, body.Name)
+		others.bodies = append(others.bodies, body.State)
+		others.masses = append(others.masses, body.Mass)
+	}
+	fh.others = &others
+	fh.self = -1
+}
+
+// For returns a handler of the same bodies acting on the named one, which is left out of
+// the sums. The bodies, and the tree of the Barnes-Hut approximation, are shared so that
+// a whole system is integrated over a step with a single tree.
+func (fh *RateFunctionHandler) For(name string) RateFunctionHandler {
+	handler := *fh
+	handler.self = -1
+	if fh.others != nil {
+		if j, ok := fh.others.index[name]; ok {
+			handler.self = j
+		}
+	}
+	return handler
+}
+
+// Find returns the state and mass of another body, false when it is not part of the
+// simulation
+func (fh *RateFunctionHandler) Find(name string) (Vector, float64, bool) {
+	if fh.others == nil {
+		return Vector{}, 0, false
+	}
+	j, ok := fh.others.index[name]
+	if !ok || j == fh.self {
+		return Vector{}, 0, false
+	}
+	return fh.others.bodies[j], fh.others.masses[j], true
+}
+
+func (fh *RateFunctionHandler) octree() *octreeNode {
+	fh.others.once.Do(func() {
+		fh.others.tree = newOctree(fh.others.bodies, fh.others.masses)
+	})
+	return fh.others.tree
+}
+
+// -------------------------------------------------------------------------------------
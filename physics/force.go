@@ -0,0 +1,156 @@
+package physics
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// -------------------------------------------------------------------------------------
+// Force models adding up to the acceleration of the body
+
+type ForceModel interface {
+	Acceleration(sigma Vector, fh *RateFunctionHandler) (float64, float64, float64)
+}
+
+// Newtonian attraction of every other body, Plummer-softened so that close approaches
+// do not blow up: a = G m d / (d² + ε²)^3/2. With a positive opening angle, the sum is
+// approximated with a Barnes-Hut tree.
+type NewtonianForce struct {
+	Softening float64
+	Theta     float64 // Opening angle of the Barnes-Hut approximation, 0 for the exact sum
+}
+
+func (force *NewtonianForce) Acceleration(sigmai Vector, fh *RateFunctionHandler) (float64, float64, float64) {
+	if fh.others == nil {
+		return 0, 0, 0
+	}
+	eps2 := force.Softening * force.Softening
+	if force.Theta > 0 {
+		self := octreeExclusion{body: fh.self}
+		if fh.self >= 0 {
+			self.at, self.mass = fh.others.bodies[fh.self], fh.others.masses[fh.self]
+		}
+		ax, ay, az := fh.octree().acceleration(sigmai, force.Theta, eps2, self)
+		return fh.G * ax, fh.G * ay, fh.G * az
+	}
+	ax, ay, az := 0., 0., 0.
+	for j, sigmaj := range fh.others.bodies {
+		muj := fh.G * fh.others.masses[j]
+		if muj == 0 || j == fh.self {
+			continue // Massless placeholders exert no force, nor does the body on itself
+		}
+		dx := sigmaj.X - sigmai.X
+		dy := sigmaj.Y - sigmai.Y
+		dz := sigmaj.Z - sigmai.Z
+		d2 := dx*dx + dy*dy + dz*dz + eps2
+		d_ij := math.Sqrt(d2)
+		Fx := muj * dx / (d2 * d_ij)
+		Fy := muj * dy / (d2 * d_ij)
+		Fz := muj * dz / (d2 * d_ij)
+		ax += Fx
+		ay += Fy
+		az += Fz
+	}
+	return ax, ay, az
+}
+
+// First post-Newtonian correction of the primary's field in the test particle limit,
+// responsible for the relativistic perihelion precession (43"/century for Mercury):
+// a = μ / (c² r³) ((4μ/r - v²) r + 4 (r·v) v)
+type PostNewtonianForce struct {
+	Primary string
+	C       float64 // Speed of light in the unit system
+}
+
+func (force *PostNewtonianForce) Acceleration(sigma Vector, fh *RateFunctionHandler) (float64, float64, float64) {
+	primary, mass, ok := fh.Find(force.Primary)
+	if !ok || mass == 0 {
+		return 0, 0, 0
+	}
+	mu := fh.G * mass
+	rx, ry, rz := sigma.X-primary.X, sigma.Y-primary.Y, sigma.Z-primary.Z
+	vx, vy, vz := sigma.Vx-primary.Vx, sigma.Vy-primary.Vy, sigma.Vz-primary.Vz
+	r := math.Sqrt(rx*rx + ry*ry + rz*rz)
+	v2 := vx*vx + vy*vy + vz*vz
+	rv := rx*vx + ry*vy + rz*vz
+	k := mu / (force.C * force.C * r * r * r)
+	radial := 4*mu/r - v2
+	return k * (radial*rx + 4*rv*vx), k * (radial*ry + 4*rv*vy), k * (radial*rz + 4*rv*vz)
+}
+
+// Oblateness of the primary through its J2 zonal harmonic, its equator being the x-y plane
+type J2Force struct {
+	Primary string
+	J2      float64
+	Radius  float64 // Equatorial radius of the primary
+}
+
+func (force *J2Force) Acceleration(sigma Vector, fh *RateFunctionHandler) (float64, float64, float64) {
+	primary, mass, ok := fh.Find(force.Primary)
+	if !ok || mass == 0 {
+		return 0, 0, 0
+	}
+	mu := fh.G * mass
+	x, y, z := sigma.X-primary.X, sigma.Y-primary.Y, sigma.Z-primary.Z
+	r2 := x*x + y*y + z*z
+	r := math.Sqrt(r2)
+	k := -1.5 * force.J2 * mu * force.Radius * force.Radius / (r2 * r2 * r)
+	zr := 5 * z * z / r2
+	return k * x * (1 - zr), k * y * (1 - zr), k * z * (3 - zr)
+}
+
+// -------------------------------------------------------------------------------------
+
+// -------------------------------------------------------------------------------------
+// Force model selection by name
+
+const NEWTON = "newton"
+const POST_NEWTONIAN = "1pn"
+const J2 = "j2"
+
+// Parameters of the force models
+type ForceOptions struct {
+	Softening     float64
+	Theta         float64 // Opening angle of the Barnes-Hut approximation
+	Primary       string  // Body the 1pn and j2 forces are computed around
+	C             float64 // Speed of light in the unit system
+	J2            float64
+	PrimaryRadius float64
+}
+
+// NewForceModels returns the force models of a comma separated list of names
+func NewForceModels(names string, options ForceOptions) ([]ForceModel, error) {
+	forces := make([]ForceModel, 0)
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case NEWTON:
+			if options.Softening < 0 {
+				return nil, fmt.Errorf("softening must not be negative, got %v", options.Softening)
+			}
+			if options.Theta < 0 {
+				return nil, fmt.Errorf("opening angle must not be negative, got %v", options.Theta)
+			}
+			forces = append(forces, &NewtonianForce{Softening: options.Softening, Theta: options.Theta})
+		case POST_NEWTONIAN:
+			if options.Primary == "" {
+				return nil, fmt.Errorf("force %q needs a primary body", name)
+			}
+			forces = append(forces, &PostNewtonianForce{Primary: options.Primary, C: options.C})
+		case J2:
+			if options.Primary == "" {
+				return nil, fmt.Errorf("force %q needs a primary body", name)
+			}
+			if options.PrimaryRadius <= 0 {
+				return nil, fmt.Errorf("force %q needs a positive primary radius", name)
+			}
+			forces = append(forces, &J2Force{Primary: options.Primary, J2: options.J2, Radius: options.PrimaryRadius})
+		default:
+			return nil, fmt.Errorf("unknown force %q", name)
+		}
+	}
+	return forces, nil
+}
+
+// -------------------------------------------------------------------------------------
@@ -0,0 +1,173 @@
+package physics
+
+import "math"
+
+// -------------------------------------------------------------------------------------
+// Barnes-Hut octree
+//
+// Space is split recursively into octants until every leaf holds a single body, and
+// every cell keeps the total mass and center of mass of the bodies it contains. A cell
+// seen under an angle smaller than the opening angle θ (size / distance < θ) acts as a
+// point mass at its center of mass, which brings the sum down to O(log N) per body.
+
+// Cells are no longer split past that depth, so that bodies at the same position end up
+// in a single leaf
+const OCTREE_MAX_DEPTH = 32
+
+type octreeNode struct {
+	cx, cy, cz float64 // Center of the cell
+	half       float64 // Half of the side of the cell
+	mass       float64
+	x, y, z    float64 // Center of mass
+	count      int
+	body       int // Index of the body of a leaf, -1 for cells and leaves of several bodies
+	children   *[8]*octreeNode
+}
+
+// Builds the tree of the massive bodies, massless ones exert no force
+func newOctree(bodies []Vector, masses []float64) *octreeNode {
+	minX, minY, minZ := math.Inf(1), math.Inf(1), math.Inf(1)
+	maxX, maxY, maxZ := math.Inf(-1), math.Inf(-1), math.Inf(-1)
+	for j, body := range bodies {
+		if masses[j] == 0 {
+			continue
+		}
+		minX, maxX = math.Min(minX, body.X), math.Max(maxX, body.X)
+		minY, maxY = math.Min(minY, body.Y), math.Max(maxY, body.Y)
+		minZ, maxZ = math.Min(minZ, body.Z), math.Max(maxZ, body.Z)
+	}
+	root := &octreeNode{body: -1}
+	if math.IsInf(minX, 1) {
+		return root
+	}
+	root.cx, root.cy, root.cz = (minX+maxX)/2, (minY+maxY)/2, (minZ+maxZ)/2
+	// Slightly larger than the bounding box so that no body sits on its boundary
+	root.half = math.Max(maxX-minX, math.Max(maxY-minY, maxZ-minZ))/2*(1+1e-9) + 1e-300
+	for j, body := range bodies {
+		if masses[j] != 0 {
+			root.insert(j, body.X, body.Y, body.Z, masses[j], 0)
+		}
+	}
+	return root
+}
+
+// Index of the child a position belongs to
+func (node *octreeNode) octant(x, y, z float64) int {
+	i := 0
+	if x >= node.cx {
+		i |= 1
+	}
+	if y >= node.cy {
+		i |= 2
+	}
+	if z >= node.cz {
+		i |= 4
+	}
+	return i
+}
+
+func (node *octreeNode) child(x, y, z float64) *octreeNode {
+	i := node.octant(x, y, z)
+	quarter := node.half / 2
+	cx, cy, cz := node.cx-quarter, node.cy-quarter, node.cz-quarter
+	if i&1 != 0 {
+		cx = node.cx + quarter
+	}
+	if i&2 != 0 {
+		cy = node.cy + quarter
+	}
+	if i&4 != 0 {
+		cz = node.cz + quarter
+	}
+	if node.children[i] == nil {
+		node.children[i] = &octreeNode{cx: cx, cy: cy, cz: cz, half: quarter, body: -1}
+	}
+	return node.children[i]
+}
+
+func (node *octreeNode) insert(body int, x, y, z, mass float64, depth int) {
+	if node.count == 0 {
+		node.body = body
+		node.x, node.y, node.z, node.mass = x, y, z, mass
+		node.count = 1
+		return
+	}
+	if node.children == nil && depth < OCTREE_MAX_DEPTH {
+		// The body of the leaf moves down to the octant it belongs to
+		node.children = &[8]*octreeNode{}
+		node.child(node.x, node.y, node.z).insert(node.body, node.x, node.y, node.z, node.mass, depth+1)
+	}
+	if node.children != nil {
+		node.child(x, y, z).insert(body, x, y, z, mass, depth+1)
+	}
+	node.body = -1
+	total := node.mass + mass
+	node.x = (node.x*node.mass + x*mass) / total
+	node.y = (node.y*node.mass + y*mass) / total
+	node.z = (node.z*node.mass + z*mass) / total
+	node.mass = total
+	node.count++
+}
+
+// Body left out of the sum, at the position and with the mass it was inserted with
+type octreeExclusion struct {
+	body int // -1 when the cell does not contain it
+	at   Vector
+	mass float64
+}
+
+// Acceleration per unit of G exerted on sigma by the bodies of the cell, leaving out the
+// excluded body. A cell containing it acts with the mass and center of mass of the other
+// bodies, as sigma may be far from the position the body was inserted at, e.g. during the
+// substeps of a solver.
+func (node *octreeNode) acceleration(sigma Vector, theta float64, eps2 float64, self octreeExclusion) (float64, float64, float64) {
+	if node.count == 0 || (self.body >= 0 && node.body == self.body) {
+		return 0, 0, 0
+	}
+	mass, x, y, z := node.mass, node.x, node.y, node.z
+	if self.body >= 0 {
+		mass -= self.mass
+		if mass <= 0 {
+			return 0, 0, 0
+		}
+		x = (node.x*node.mass - self.at.X*self.mass) / mass
+		y = (node.y*node.mass - self.at.Y*self.mass) / mass
+		z = (node.z*node.mass - self.at.Z*self.mass) / mass
+	}
+	dx := x - sigma.X
+	dy := y - sigma.Y
+	dz := z - sigma.Z
+	d2 := dx*dx + dy*dy + dz*dz
+	size := 2 * node.half
+	if node.children == nil || size*size < theta*theta*d2 {
+		d2 += eps2
+		if d2 == 0 {
+			return 0, 0, 0
+		}
+		d := math.Sqrt(d2)
+		k := mass / (d2 * d)
+		return k * dx, k * dy, k * dz
+	}
+	// Only the child the body was inserted in contains it
+	octant := -1
+	if self.body >= 0 {
+		octant = node.octant(self.at.X, self.at.Y, self.at.Z)
+	}
+	ax, ay, az := 0., 0., 0.
+	for i, child := range node.children {
+		if child == nil {
+			continue
+		}
+		exclusion := self
+		if i != octant {
+			exclusion = octreeExclusion{body: -1}
+		}
+		cx, cy, cz := child.acceleration(sigma, theta, eps2, exclusion)
+		ax += cx
+		ay += cy
+		az += cz
+	}
+	return ax, ay, az
+}
+
+// -------------------------------------------------------------------------------------
@@ -0,0 +1,77 @@
+package physics
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Random cloud of bodies, the first one being the integrated body
+func randomBodies(n int) []Body {
+	random := rand.New(rand.NewSource(1))
+	bodies := make([]Body, n)
+	for j := range bodies {
+		bodies[j] = Body{
+			Name:  fmt.Sprint(j),
+			Mass:  random.Float64(),
+			State: Vector{X: random.NormFloat64(), Y: random.NormFloat64(), Z: random.NormFloat64()},
+		}
+	}
+	return bodies
+}
+
+func accelerationOf(t *testing.T, bodies []Body, theta float64) (float64, float64, float64) {
+	t.Helper()
+	fh := RateFunctionHandler{G: 1, Forces: []ForceModel{&NewtonianForce{Softening: 1e-3, Theta: theta}}}
+	fh.Update(bodies)
+	handler := fh.For(bodies[0].Name)
+	rate := handler.Evaluate(bodies[0].State)
+	return rate.Vx, rate.Vy, rate.Vz
+}
+
+func TestBarnesHutApproximatesTheDirectSum(t *testing.T) {
+	bodies := randomBodies(2000)
+	ex, ey, ez := accelerationOf(t, bodies, 0)
+	norm := math.Sqrt(ex*ex + ey*ey + ez*ez)
+	for _, theta := range []float64{0.1, 0.5} {
+		ax, ay, az := accelerationOf(t, bodies, theta)
+		if e := math.Sqrt(math.Pow(ax-ex, 2)+math.Pow(ay-ey, 2)+math.Pow(az-ez, 2)) / norm; e > theta*theta/10 {
+			t.Errorf("theta %v: relative error %e", theta, e)
+		}
+	}
+}
+
+func TestBodyExertsNoForceOnItself(t *testing.T) {
+	bodies := []Body{{Name: "A", Mass: 1}, {Name: "B", Mass: 1, State: Vector{X: 1}}}
+	for _, theta := range []float64{0, 0.5} {
+		fh := RateFunctionHandler{G: 1, Forces: []ForceModel{&NewtonianForce{Theta: theta}}}
+		fh.Update(bodies)
+		handler := fh.For("A")
+		// Half way through a step, away from its own position at the start of the step
+		rate := handler.Evaluate(Vector{X: 0.5})
+		if math.Abs(rate.Vx-4) > 1e-12 {
+			t.Errorf("theta %v: expected an acceleration of 4 towards B only, got %v", theta, rate.Vx)
+		}
+	}
+}
+
+func TestBodyInACellExertsNoForceOnItself(t *testing.T) {
+	// A shares a cell with B and C, which passes the opening test from x = -1
+	bodies := []Body{{Name: "A", Mass: 1}, {Name: "B", Mass: 1, State: Vector{X: 0.01}}, {Name: "C", Mass: 1, State: Vector{X: 0.02}}}
+	fh := RateFunctionHandler{G: 1, Forces: []ForceModel{&NewtonianForce{Theta: 0.5}}}
+	fh.Update(bodies)
+	handler := fh.For("A")
+	exact := 1/(1.01*1.01) + 1/(1.02*1.02)
+	if rate := handler.Evaluate(Vector{X: -1}); math.Abs(rate.Vx-exact) > 1e-3 {
+		t.Errorf("expected an acceleration of %v towards B and C only, got %v", exact, rate.Vx)
+	}
+
+	// Bodies at the same position end up in a leaf of several bodies
+	bodies = []Body{{Name: "A", Mass: 1}, {Name: "B", Mass: 2}, {Name: "C", Mass: 1, State: Vector{X: 4}}}
+	fh.Update(bodies)
+	handler = fh.For("A")
+	if rate := handler.Evaluate(Vector{X: -1}); math.Abs(rate.Vx-2.04) > 1e-12 {
+		t.Errorf("expected an acceleration of 2.04 towards B and C only, got %v", rate.Vx)
+	}
+}
@@ -0,0 +1,207 @@
+package physics
+
+import (
+	"fmt"
+	"math"
+)
+
+// -------------------------------------------------------------------------------------
+// Interface to abstract solver properties
+
+type Solver interface {
+	Step(sigma Vector, f RateFunction, dt float64) Vector
+}
+
+// Solvers estimating their local error propose the step size of the next round
+type AdaptiveSolver interface {
+	Solver
+	ProposeStep(dt float64) float64
+}
+
+// -------------------------------------------------------------------------------------
+
+// -------------------------------------------------------------------------------------
+// Order 4 Runge-Kutta solver
+
+type RungeKutta4Solver struct {
+}
+
+func (rks *RungeKutta4Solver) Step(sigma Vector, f RateFunction, dt float64) Vector {
+	F1 := f(sigma)
+	F2 := f(sigma.Add(F1.ScalarMultiply(dt / 2.0)))
+	F3 := f(sigma.Add(F2.ScalarMultiply(dt / 2.0)))
+	F4 := f(sigma.Add(F3.ScalarMultiply(dt)))
+	sum_F := F1.Add(F2.ScalarMultiply(2.0))
+	sum_F = sum_F.Add(F3.ScalarMultiply(2.0))
+	sum_F = sum_F.Add(F4)
+	return sigma.Add(sum_F.ScalarMultiply(dt / 6.0))
+}
+
+// -------------------------------------------------------------------------------------
+
+// -------------------------------------------------------------------------------------
+// Symplectic solvers
+//
+// The gravitational acceleration only depends on positions, so the rate function can be
+// split into a drift (positions advanced with the current speeds) and a kick (speeds
+// advanced with the acceleration at the current positions). Compositions of drifts and
+// kicks keep the energy error bounded over long runs, unlike RungeKutta4Solver.
+
+func drift(sigma Vector, dt float64) Vector {
+	sigma.X += sigma.Vx * dt
+	sigma.Y += sigma.Vy * dt
+	sigma.Z += sigma.Vz * dt
+	return sigma
+}
+
+func kick(sigma Vector, f RateFunction, dt float64) Vector {
+	rate := f(sigma)
+	sigma.Vx += rate.Vx * dt
+	sigma.Vy += rate.Vy * dt
+	sigma.Vz += rate.Vz * dt
+	return sigma
+}
+
+// Coefficients of the 4th order triple jump composition shared by Yoshida and Forest-Ruth
+var cbrt2 = math.Cbrt(2.0)
+var tripleJumpW1 = 1.0 / (2.0 - cbrt2)
+var tripleJumpW0 = -cbrt2 / (2.0 - cbrt2)
+
+// Order 2 leapfrog in its velocity-Verlet (kick-drift-kick) form
+type LeapfrogSolver struct {
+}
+
+func (ls *LeapfrogSolver) Step(sigma Vector, f RateFunction, dt float64) Vector {
+	sigma = kick(sigma, f, dt/2.0)
+	sigma = drift(sigma, dt)
+	return kick(sigma, f, dt/2.0)
+}
+
+// Order 4 Yoshida solver: triple jump composition of velocity-Verlet steps
+type Yoshida4Solver struct {
+}
+
+func (ys *Yoshida4Solver) Step(sigma Vector, f RateFunction, dt float64) Vector {
+	sigma = kick(sigma, f, tripleJumpW1*dt/2.0)
+	sigma = drift(sigma, tripleJumpW1*dt)
+	sigma = kick(sigma, f, (tripleJumpW0+tripleJumpW1)*dt/2.0)
+	sigma = drift(sigma, tripleJumpW0*dt)
+	sigma = kick(sigma, f, (tripleJumpW0+tripleJumpW1)*dt/2.0)
+	sigma = drift(sigma, tripleJumpW1*dt)
+	return kick(sigma, f, tripleJumpW1*dt/2.0)
+}
+
+// Order 4 Forest-Ruth solver: triple jump composition of position-Verlet steps
+type ForestRuthSolver struct {
+}
+
+func (frs *ForestRuthSolver) Step(sigma Vector, f RateFunction, dt float64) Vector {
+	sigma = drift(sigma, tripleJumpW1*dt/2.0)
+	sigma = kick(sigma, f, tripleJumpW1*dt)
+	sigma = drift(sigma, (tripleJumpW0+tripleJumpW1)*dt/2.0)
+	sigma = kick(sigma, f, tripleJumpW0*dt)
+	sigma = drift(sigma, (tripleJumpW0+tripleJumpW1)*dt/2.0)
+	sigma = kick(sigma, f, tripleJumpW1*dt)
+	return drift(sigma, tripleJumpW1*dt/2.0)
+}
+
+// -------------------------------------------------------------------------------------
+
+// -------------------------------------------------------------------------------------
+// Embedded Runge-Kutta 5(4) Dormand-Prince solver
+//
+// The step size is imposed by the server for the whole round, so a step can never be
+// rejected: the error estimate of the step just taken is used to propose the next one.
+
+var dopriC = []float64{0, 1.0 / 5.0, 3.0 / 10.0, 4.0 / 5.0, 8.0 / 9.0, 1, 1}
+var dopriA = [][]float64{
+	{},
+	{1.0 / 5.0},
+	{3.0 / 40.0, 9.0 / 40.0},
+	{44.0 / 45.0, -56.0 / 15.0, 32.0 / 9.0},
+	{19372.0 / 6561.0, -25360.0 / 2187.0, 64448.0 / 6561.0, -212.0 / 729.0},
+	{9017.0 / 3168.0, -355.0 / 33.0, 46732.0 / 5247.0, 49.0 / 176.0, -5103.0 / 18656.0},
+	{35.0 / 384.0, 0, 500.0 / 1113.0, 125.0 / 192.0, -2187.0 / 6784.0, 11.0 / 84.0},
+}
+
+// Difference between the order 5 and the order 4 weights
+var dopriE = []float64{71.0 / 57600.0, 0, -71.0 / 16695.0, 71.0 / 1920.0, -17253.0 / 339200.0, 22.0 / 525.0, -1.0 / 40.0}
+
+const DOPRI_SAFETY = 0.9
+const DOPRI_MIN_FACTOR = 0.2
+const DOPRI_MAX_FACTOR = 5.0
+
+type DormandPrinceSolver struct {
+	Tolerance float64 // Local error tolerance
+	error     float64
+}
+
+func combine(sigma Vector, dt float64, coefficients []float64, rates []Vector) Vector {
+	for i, coefficient := range coefficients {
+		sigma = sigma.Add(rates[i].ScalarMultiply(coefficient * dt))
+	}
+	return sigma
+}
+
+func (dps *DormandPrinceSolver) Step(sigma Vector, f RateFunction, dt float64) Vector {
+	rates := make([]Vector, len(dopriC))
+	rates[0] = f(sigma)
+	for i := 1; i < len(dopriC); i++ {
+		rates[i] = f(combine(sigma, dt, dopriA[i], rates))
+	}
+	// First same as last: the last stage is evaluated at the order 5 solution
+	next := combine(sigma, dt, dopriA[len(dopriA)-1], rates)
+	estimate := combine(Vector{}, dt, dopriE, rates)
+	dps.error = 0
+	for _, pair := range [][2]float64{
+		{estimate.X, next.X}, {estimate.Y, next.Y}, {estimate.Z, next.Z},
+		{estimate.Vx, next.Vx}, {estimate.Vy, next.Vy}, {estimate.Vz, next.Vz},
+	} {
+		scaled := math.Abs(pair[0]) / (dps.Tolerance * math.Max(1, math.Abs(pair[1])))
+		dps.error = math.Max(dps.error, scaled)
+	}
+	return next
+}
+
+func (dps *DormandPrinceSolver) ProposeStep(dt float64) float64 {
+	if dps.error == 0 {
+		return dt * DOPRI_MAX_FACTOR
+	}
+	factor := DOPRI_SAFETY * math.Pow(dps.error, -1.0/5.0)
+	return dt * math.Min(DOPRI_MAX_FACTOR, math.Max(DOPRI_MIN_FACTOR, factor))
+}
+
+// -------------------------------------------------------------------------------------
+
+// -------------------------------------------------------------------------------------
+// Solver selection by name
+
+const RK4 = "rk4"
+const LEAPFROG = "leapfrog"
+const YOSHIDA4 = "yoshida4"
+const FOREST_RUTH = "forest-ruth"
+const DOPRI5 = "dopri5"
+
+// NewSolver returns the solver of the given name, the tolerance only applies to adaptive
+// solvers
+func NewSolver(name string, tolerance float64) (Solver, error) {
+	switch name {
+	case RK4:
+		return &RungeKutta4Solver{}, nil
+	case LEAPFROG:
+		return &LeapfrogSolver{}, nil
+	case YOSHIDA4:
+		return &Yoshida4Solver{}, nil
+	case FOREST_RUTH:
+		return &ForestRuthSolver{}, nil
+	case DOPRI5:
+		// The error estimate is scaled by the tolerance
+		if tolerance <= 0 {
+			return nil, fmt.Errorf("solver %q needs a positive tolerance, got %v", name, tolerance)
+		}
+		return &DormandPrinceSolver{Tolerance: tolerance}, nil
+	}
+	return nil, fmt.Errorf("unknown solver %q", name)
+}
+
+// -------------------------------------------------------------------------------------
@@ -0,0 +1,78 @@
+package physics
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// Circular orbit of radius 1 around a unit mass at the origin, with period 2π
+func circularOrbit(t *testing.T, name string, steps int) (Vector, Vector) {
+	t.Helper()
+	solver, err := NewSolver(name, 1e-9)
+	if err != nil {
+		t.Fatalf("could not select %v: %v", name, err)
+	}
+	fh := RateFunctionHandler{G: 1, Forces: []ForceModel{&NewtonianForce{}}}
+	fh.Update([]Body{{Name: "S", Mass: 1}})
+	start := Vector{X: 1, Vy: 1}
+	sigma := start
+	dt := 2 * math.Pi / float64(steps)
+	for i := 0; i < steps; i++ {
+		sigma = solver.Step(sigma, fh.Evaluate, dt)
+	}
+	return start, sigma
+}
+
+func TestSolversCloseTheCircularOrbit(t *testing.T) {
+	for _, name := range []string{RK4, LEAPFROG, YOSHIDA4, FOREST_RUTH, DOPRI5} {
+		start, end := circularOrbit(t, name, 2000)
+		if d := math.Hypot(end.X-start.X, end.Y-start.Y); d > 1e-4 {
+			t.Errorf("%v: ended %v away from the start after one period", name, d)
+		}
+	}
+}
+
+func TestUnknownSolverIsRejected(t *testing.T) {
+	if _, err := NewSolver("euler", 0); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
+
+func TestAdaptiveSolverNeedsAPositiveTolerance(t *testing.T) {
+	for _, tolerance := range []float64{0, -1e-9} {
+		if _, err := NewSolver(DOPRI5, tolerance); err == nil {
+			t.Errorf("expected a tolerance of %v to be rejected", tolerance)
+		}
+	}
+}
+
+func TestSofteningBoundsTheAcceleration(t *testing.T) {
+	fh := RateFunctionHandler{G: 1, Forces: []ForceModel{&NewtonianForce{Softening: 0.1}}}
+	fh.Update([]Body{{Name: "S", Mass: 1, State: Vector{X: 1e-9}}})
+	rate := fh.Evaluate(Vector{})
+	if math.IsInf(rate.Vx, 0) || math.IsNaN(rate.Vx) || rate.Vx > 1e-6 {
+		t.Fatalf("unexpected acceleration at a close approach: %v", rate.Vx)
+	}
+}
+
+func TestTestParticlesExertNoForce(t *testing.T) {
+	fh := RateFunctionHandler{G: 1, Forces: []ForceModel{&NewtonianForce{}}}
+	fh.Update([]Body{{Name: "P", Mass: 1, State: Vector{X: 1}, TestParticle: true}})
+	if rate := fh.Evaluate(Vector{}); rate.Vx != 0 {
+		t.Fatalf("expected no force from a test particle, got %v", rate.Vx)
+	}
+}
+
+func TestForceModelsNeedAPrimary(t *testing.T) {
+	if _, err := NewForceModels("newton,1pn", ForceOptions{}); err == nil {
+		t.Fatalf("expected 1pn without a primary to be rejected")
+	}
+	if _, err := NewForceModels("newtn", ForceOptions{}); err == nil || !strings.Contains(err.Error(), "unknown force") {
+		t.Fatalf("expected a misspelled force to be reported as unknown, got %v", err)
+	}
+	forces, err := NewForceModels("newton, 1pn, j2", ForceOptions{Primary: "S", C: 1e4, PrimaryRadius: 0.01})
+	if err != nil || len(forces) != 3 {
+		t.Fatalf("expected 3 force models, got %v: %v", forces, err)
+	}
+}
@@ -0,0 +1,94 @@
+package physics
+
+import (
+	"fmt"
+	"math"
+)
+
+// -------------------------------------------------------------------------------------
+// Thrust of a spacecraft
+
+const PROGRADE = "prograde"
+const RADIAL = "radial"
+const FIXED = "fixed"
+
+// Constant acceleration over [Start, Stop) of simulation time, burning MassFlow of the
+// body's mass per unit of time
+type ThrustSegment struct {
+	Start        float64 `json:"start"`
+	Stop         float64 `json:"stop"`
+	Direction    string  `json:"direction"` // PROGRADE, RADIAL (outwards) or FIXED
+	Acceleration float64 `json:"acceleration"`
+	MassFlow     float64 `json:"mass_flow"`
+	// Direction of a FIXED thrust, normalized
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+func (segment *ThrustSegment) Validate() error {
+	if !(segment.Stop > segment.Start) {
+		return fmt.Errorf("thrust segment stops at %v before it starts at %v", segment.Stop, segment.Start)
+	}
+	if segment.Acceleration < 0 || segment.MassFlow < 0 {
+		return fmt.Errorf("thrust segment needs a non-negative acceleration and mass flow, got %v and %v", segment.Acceleration, segment.MassFlow)
+	}
+	switch segment.Direction {
+	case PROGRADE, RADIAL:
+	case FIXED:
+		if segment.X == 0 && segment.Y == 0 && segment.Z == 0 {
+			return fmt.Errorf("fixed thrust segment needs a direction")
+		}
+	default:
+		return fmt.Errorf("unknown thrust direction %q", segment.Direction)
+	}
+	return nil
+}
+
+// Schedule of thrust segments, prograde and radial directions being relative to the
+// primary, or to the origin when there is none. As rate functions do not depend on time,
+// the schedule is evaluated at Time, which the integrator of the body sets to the start
+// of each step: a segment acts on the whole steps starting within it.
+type ThrustForce struct {
+	Segments []ThrustSegment
+	Primary  string
+	Time     float64
+}
+
+// Active returns the segment the schedule is in at Time, nil when coasting
+func (force *ThrustForce) Active() *ThrustSegment {
+	for i := range force.Segments {
+		if force.Time >= force.Segments[i].Start && force.Time < force.Segments[i].Stop {
+			return &force.Segments[i]
+		}
+	}
+	return nil
+}
+
+func (force *ThrustForce) Acceleration(sigma Vector, fh *RateFunctionHandler) (float64, float64, float64) {
+	segment := force.Active()
+	if segment == nil || segment.Acceleration == 0 {
+		return 0, 0, 0
+	}
+	relative := sigma
+	if force.Primary != "" {
+		if primary, _, ok := fh.Find(force.Primary); ok {
+			relative = sigma.Add(primary.ScalarMultiply(-1))
+		}
+	}
+	x, y, z := segment.X, segment.Y, segment.Z
+	switch segment.Direction {
+	case PROGRADE:
+		x, y, z = relative.Vx, relative.Vy, relative.Vz
+	case RADIAL:
+		x, y, z = relative.X, relative.Y, relative.Z
+	}
+	norm := math.Sqrt(x*x + y*y + z*z)
+	if norm == 0 {
+		return 0, 0, 0
+	}
+	k := segment.Acceleration / norm
+	return k * x, k * y, k * z
+}
+
+// -------------------------------------------------------------------------------------
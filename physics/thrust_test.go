@@ -0,0 +1,50 @@
+package physics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestThrustFollowsItsSchedule(t *testing.T) {
+	thrust := &ThrustForce{Segments: []ThrustSegment{
+		{Start: 1, Stop: 2, Direction: PROGRADE, Acceleration: 0.5},
+		{Start: 3, Stop: 4, Direction: FIXED, Acceleration: 2, Z: -1},
+	}}
+	fh := RateFunctionHandler{Forces: []ForceModel{thrust}}
+	fh.Update(nil)
+	solver, _ := NewSolver(RK4, 0)
+	sigma := Vector{Vx: 3, Vy: 4}
+	for step := 0; step < 5; step++ {
+		thrust.Time = float64(step)
+		sigma = solver.Step(sigma, fh.Evaluate, 1)
+	}
+	// 0.5 along (0.6, 0.8) while in the first segment, then 2 along -z
+	if math.Abs(sigma.Vx-3.3) > 1e-12 || math.Abs(sigma.Vy-4.4) > 1e-12 || math.Abs(sigma.Vz+2) > 1e-12 {
+		t.Fatalf("unexpected velocity after the schedule: %v", sigma)
+	}
+	if thrust.Time = 4; thrust.Active() != nil {
+		t.Fatalf("expected the schedule to coast at its end")
+	}
+}
+
+func TestThrustIsRelativeToThePrimary(t *testing.T) {
+	thrust := &ThrustForce{Primary: "E", Segments: []ThrustSegment{{Start: 0, Stop: 1, Direction: RADIAL, Acceleration: 1}}}
+	fh := RateFunctionHandler{Forces: []ForceModel{thrust}}
+	fh.Update([]Body{{Name: "E", State: Vector{X: 10, Y: 5}}})
+	if rate := fh.Evaluate(Vector{X: 10, Y: 7}); rate.Vx != 0 || rate.Vy != 1 {
+		t.Fatalf("expected a radial acceleration away from E, got %v", rate)
+	}
+}
+
+func TestInvalidThrustSegmentsAreRejected(t *testing.T) {
+	for _, segment := range []ThrustSegment{
+		{Start: 1, Stop: 1, Direction: PROGRADE},
+		{Start: 0, Stop: 1, Direction: "sideways"},
+		{Start: 0, Stop: 1, Direction: FIXED, Acceleration: 1},
+		{Start: 0, Stop: 1, Direction: RADIAL, MassFlow: -1},
+	} {
+		if err := segment.Validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", segment)
+		}
+	}
+}
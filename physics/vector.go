@@ -0,0 +1,45 @@
+package physics
+
+// -------------------------------------------------------------------------------------
+// Vector for hamiltonian calculations
+
+// Position and speed of a body, the state integrated by the solvers
+type Vector struct {
+	X  float64
+	Y  float64
+	Z  float64
+	Vx float64
+	Vy float64
+	Vz float64
+}
+
+func (sigma1 *Vector) Add(sigma2 Vector) Vector {
+	return Vector{
+		X:  sigma1.X + sigma2.X,
+		Y:  sigma1.Y + sigma2.Y,
+		Z:  sigma1.Z + sigma2.Z,
+		Vx: sigma1.Vx + sigma2.Vx,
+		Vy: sigma1.Vy + sigma2.Vy,
+		Vz: sigma1.Vz + sigma2.Vz,
+	}
+}
+
+func (sigma1 *Vector) ScalarMultiply(lambda float64) Vector {
+	return Vector{
+		X:  sigma1.X * lambda,
+		Y:  sigma1.Y * lambda,
+		Z:  sigma1.Z * lambda,
+		Vx: sigma1.Vx * lambda,
+		Vy: sigma1.Vy * lambda,
+		Vz: sigma1.Vz * lambda,
+	}
+}
+
+// -------------------------------------------------------------------------------------
+
+// -------------------------------------------------------------------------------------
+// Function type to pass the ODE rate function as arument of solver
+
+type RateFunction func(Vector) Vector
+
+// -------------------------------------------------------------------------------------
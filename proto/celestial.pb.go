@@ -20,37 +20,690 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type Units int32
+
+const (
+	Units_CANONICAL    Units = 0 // G = 1
+	Units_SI           Units = 1 // m, kg, s
+	Units_ASTRONOMICAL Units = 2 // AU, solar mass, day
+)
+
+// Enum value maps for Units.
+var (
+	Units_name = map[int32]string{
+		0: "CANONICAL",
+		1: "SI",
+		2: "ASTRONOMICAL",
+	}
+	Units_value = map[string]int32{
+		"CANONICAL":    0,
+		"SI":           1,
+		"ASTRONOMICAL": 2,
+	}
+)
+
+func (x Units) Enum() *Units {
+	p := new(Units)
+	*p = x
+	return p
+}
+
+func (x Units) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Units) Descriptor() protoreflect.EnumDescriptor {
+	return file_celestial_proto_enumTypes[0].Descriptor()
+}
+
+func (Units) Type() protoreflect.EnumType {
+	return &file_celestial_proto_enumTypes[0]
+}
+
+func (x Units) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Units.Descriptor instead.
+func (Units) EnumDescriptor() ([]byte, []int) {
+	return file_celestial_proto_rawDescGZIP(), []int{0}
+}
+
 type CelestialBody struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Sequence uint64  `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"` // Seqwuence in stream
-	Name     string  `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`          // Name of the celestial body
-	Mass     float64 `protobuf:"fixed64,3,opt,name=mass,proto3" json:"mass,omitempty"`        // Mass of the celestial body
-	X        float64 `protobuf:"fixed64,4,opt,name=x,proto3" json:"x,omitempty"`              // Initial x-position
-	Y        float64 `protobuf:"fixed64,5,opt,name=y,proto3" json:"y,omitempty"`              // Initial y-position
-	Vx       float64 `protobuf:"fixed64,6,opt,name=vx,proto3" json:"vx,omitempty"`            // Initial x-speed
-	Vy       float64 `protobuf:"fixed64,7,opt,name=vy,proto3" json:"vy,omitempty"`            // Initial x-speed
+	Sequence     uint64           `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`                              // Seqwuence in stream
+	Name         string           `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`                                       // Name of the celestial body
+	Mass         float64          `protobuf:"fixed64,3,opt,name=mass,proto3" json:"mass,omitempty"`                                     // Mass of the celestial body
+	X            float64          `protobuf:"fixed64,4,opt,name=x,proto3" json:"x,omitempty"`                                           // x-position
+	Y            float64          `protobuf:"fixed64,5,opt,name=y,proto3" json:"y,omitempty"`                                           // y-position
+	Vx           float64          `protobuf:"fixed64,6,opt,name=vx,proto3" json:"vx,omitempty"`                                         // x-speed
+	Vy           float64          `protobuf:"fixed64,7,opt,name=vy,proto3" json:"vy,omitempty"`                                         // y-speed
+	Z            float64          `protobuf:"fixed64,8,opt,name=z,proto3" json:"z,omitempty"`                                           // z-position (0 for planar clients)
+	Vz           float64          `protobuf:"fixed64,9,opt,name=vz,proto3" json:"vz,omitempty"`                                         // z-speed (0 for planar clients)
+	Dt           float64          `protobuf:"fixed64,10,opt,name=dt,proto3" json:"dt,omitempty"`                                        // Step size proposed by the client for the next round
+	JoinRound    uint64           `protobuf:"varint,11,opt,name=join_round,json=joinRound,proto3" json:"join_round,omitempty"`          // Round at which the body enters a running simulation
+	Radius       float64          `protobuf:"fixed64,12,opt,name=radius,proto3" json:"radius,omitempty"`                                // Radius used for collision detection (0 for a point mass)
+	Solver       string           `protobuf:"bytes,13,opt,name=solver,proto3" json:"solver,omitempty"`                                  // Integrator used by the client
+	Units        Units            `protobuf:"varint,14,opt,name=units,proto3,enum=taiyoukei.Units" json:"units,omitempty"`              // Unit system of the state, must match the server's
+	TestParticle bool             `protobuf:"varint,15,opt,name=test_particle,json=testParticle,proto3" json:"test_particle,omitempty"` // Integrated in the field of the others but exerts no force
+	Group        []*CelestialBody `protobuf:"bytes,16,rep,name=group,proto3" json:"group,omitempty"`                                    // Further bodies integrated by the same client, sent along with this body
+	Thrusting    bool             `protobuf:"varint,17,opt,name=thrusting,proto3" json:"thrusting,omitempty"`                           // Accelerated by its own thrust over the last round, beyond the forces of the simulation
+}
+
+func (x *CelestialBody) Reset() {
+	*x = CelestialBody{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_celestial_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CelestialBody) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CelestialBody) ProtoMessage() {}
+
+func (x *CelestialBody) ProtoReflect() protoreflect.Message {
+	mi := &file_celestial_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CelestialBody.ProtoReflect.Descriptor instead.
+func (*CelestialBody) Descriptor() ([]byte, []int) {
+	return file_celestial_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CelestialBody) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *CelestialBody) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CelestialBody) GetMass() float64 {
+	if x != nil {
+		return x.Mass
+	}
+	return 0
+}
+
+func (x *CelestialBody) GetX() float64 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *CelestialBody) GetY() float64 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *CelestialBody) GetVx() float64 {
+	if x != nil {
+		return x.Vx
+	}
+	return 0
+}
+
+func (x *CelestialBody) GetVy() float64 {
+	if x != nil {
+		return x.Vy
+	}
+	return 0
+}
+
+func (x *CelestialBody) GetZ() float64 {
+	if x != nil {
+		return x.Z
+	}
+	return 0
+}
+
+func (x *CelestialBody) GetVz() float64 {
+	if x != nil {
+		return x.Vz
+	}
+	return 0
+}
+
+func (x *CelestialBody) GetDt() float64 {
+	if x != nil {
+		return x.Dt
+	}
+	return 0
+}
+
+func (x *CelestialBody) GetJoinRound() uint64 {
+	if x != nil {
+		return x.JoinRound
+	}
+	return 0
+}
+
+func (x *CelestialBody) GetRadius() float64 {
+	if x != nil {
+		return x.Radius
+	}
+	return 0
+}
+
+func (x *CelestialBody) GetSolver() string {
+	if x != nil {
+		return x.Solver
+	}
+	return ""
+}
+
+func (x *CelestialBody) GetUnits() Units {
+	if x != nil {
+		return x.Units
+	}
+	return Units_CANONICAL
+}
+
+func (x *CelestialBody) GetTestParticle() bool {
+	if x != nil {
+		return x.TestParticle
+	}
+	return false
+}
+
+func (x *CelestialBody) GetGroup() []*CelestialBody {
+	if x != nil {
+		return x.Group
+	}
+	return nil
+}
+
+func (x *CelestialBody) GetThrusting() bool {
+	if x != nil {
+		return x.Thrusting
+	}
+	return false
+}
+
+// Filters applied by the server before sending the snapshots of a subscriber
+//
+// The server pushes the snapshot of every completed round exactly once, queuing it for
+// each subscriber; a subscriber also receives the latest round when it subscribes. When
+// a slow subscriber's queue is full, the server drops the oldest queued snapshot to make
+// room for the new one and counts it in Data.dropped, unless the subscriber is lossless:
+// the rounds then wait for it to catch up, slowing down the whole simulation.
+type CelestialBodiesPositionRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Names      []string `protobuf:"bytes,1,rep,name=names,proto3" json:"names,omitempty"`            // Bodies to send, every body when empty
+	Decimation uint64   `protobuf:"varint,2,opt,name=decimation,proto3" json:"decimation,omitempty"` // Only send the rounds that are a multiple of it, every round when 0
+	Frame      string   `protobuf:"bytes,3,opt,name=frame,proto3" json:"frame,omitempty"`            // Body the states are made relative to, or "barycenter" for the center of mass of the bodies exerting a force; the inertial frame of the simulation when empty
+	Buffer     uint32   `protobuf:"varint,4,opt,name=buffer,proto3" json:"buffer,omitempty"`         // Snapshots queued for the subscriber, 16 when 0 and at most 4096
+	Lossless   bool     `protobuf:"varint,5,opt,name=lossless,proto3" json:"lossless,omitempty"`     // Wait for the subscriber instead of dropping snapshots
+}
+
+func (x *CelestialBodiesPositionRequest) Reset() {
+	*x = CelestialBodiesPositionRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_celestial_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CelestialBodiesPositionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CelestialBodiesPositionRequest) ProtoMessage() {}
+
+func (x *CelestialBodiesPositionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_celestial_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CelestialBodiesPositionRequest.ProtoReflect.Descriptor instead.
+func (*CelestialBodiesPositionRequest) Descriptor() ([]byte, []int) {
+	return file_celestial_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CelestialBodiesPositionRequest) GetNames() []string {
+	if x != nil {
+		return x.Names
+	}
+	return nil
+}
+
+func (x *CelestialBodiesPositionRequest) GetDecimation() uint64 {
+	if x != nil {
+		return x.Decimation
+	}
+	return 0
+}
+
+func (x *CelestialBodiesPositionRequest) GetFrame() string {
+	if x != nil {
+		return x.Frame
+	}
+	return ""
+}
+
+func (x *CelestialBodiesPositionRequest) GetBuffer() uint32 {
+	if x != nil {
+		return x.Buffer
+	}
+	return 0
+}
+
+func (x *CelestialBodiesPositionRequest) GetLossless() bool {
+	if x != nil {
+		return x.Lossless
+	}
+	return false
+}
+
+// Rounds kept by the server, see its -history flag; older rounds are no longer available
+type HistoryRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Names     []string `protobuf:"bytes,1,rep,name=names,proto3" json:"names,omitempty"`                               // Bodies to send, every body when empty
+	FromRound uint64   `protobuf:"varint,2,opt,name=from_round,json=fromRound,proto3" json:"from_round,omitempty"`     // First round sent
+	ToRound   uint64   `protobuf:"varint,3,opt,name=to_round,json=toRound,proto3" json:"to_round,omitempty"`           // Last round sent, the latest when 0
+	FromTime  *float64 `protobuf:"fixed64,4,opt,name=from_time,json=fromTime,proto3,oneof" json:"from_time,omitempty"` // Only send the rounds at or after this time when set
+	ToTime    *float64 `protobuf:"fixed64,5,opt,name=to_time,json=toTime,proto3,oneof" json:"to_time,omitempty"`       // Only send the rounds at or before this time when set
+	Frame     string   `protobuf:"bytes,6,opt,name=frame,proto3" json:"frame,omitempty"`                               // As in CelestialBodiesPositionRequest
+}
+
+func (x *HistoryRequest) Reset() {
+	*x = HistoryRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_celestial_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryRequest) ProtoMessage() {}
+
+func (x *HistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_celestial_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryRequest.ProtoReflect.Descriptor instead.
+func (*HistoryRequest) Descriptor() ([]byte, []int) {
+	return file_celestial_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *HistoryRequest) GetNames() []string {
+	if x != nil {
+		return x.Names
+	}
+	return nil
+}
+
+func (x *HistoryRequest) GetFromRound() uint64 {
+	if x != nil {
+		return x.FromRound
+	}
+	return 0
+}
+
+func (x *HistoryRequest) GetToRound() uint64 {
+	if x != nil {
+		return x.ToRound
+	}
+	return 0
+}
+
+func (x *HistoryRequest) GetFromTime() float64 {
+	if x != nil && x.FromTime != nil {
+		return *x.FromTime
+	}
+	return 0
+}
+
+func (x *HistoryRequest) GetToTime() float64 {
+	if x != nil && x.ToTime != nil {
+		return *x.ToTime
+	}
+	return 0
+}
+
+func (x *HistoryRequest) GetFrame() string {
+	if x != nil {
+		return x.Frame
+	}
+	return ""
+}
+
+type CheckpointRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Path string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"` // File to write to, the server's -checkpoint file when empty
+}
+
+func (x *CheckpointRequest) Reset() {
+	*x = CheckpointRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_celestial_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CheckpointRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckpointRequest) ProtoMessage() {}
+
+func (x *CheckpointRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_celestial_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckpointRequest.ProtoReflect.Descriptor instead.
+func (*CheckpointRequest) Descriptor() ([]byte, []int) {
+	return file_celestial_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CheckpointRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type CheckpointReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Path  string  `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`    // File written
+	Round uint64  `protobuf:"varint,2,opt,name=round,proto3" json:"round,omitempty"` // Round saved
+	Time  float64 `protobuf:"fixed64,3,opt,name=time,proto3" json:"time,omitempty"`  // Simulation time of the round saved
+}
+
+func (x *CheckpointReply) Reset() {
+	*x = CheckpointReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_celestial_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CheckpointReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckpointReply) ProtoMessage() {}
+
+func (x *CheckpointReply) ProtoReflect() protoreflect.Message {
+	mi := &file_celestial_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckpointReply.ProtoReflect.Descriptor instead.
+func (*CheckpointReply) Descriptor() ([]byte, []int) {
+	return file_celestial_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CheckpointReply) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *CheckpointReply) GetRound() uint64 {
+	if x != nil {
+		return x.Round
+	}
+	return 0
+}
+
+func (x *CheckpointReply) GetTime() float64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+type Data struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Success   bool                      `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Content   map[string]*CelestialBody `protobuf:"bytes,2,rep,name=content,proto3" json:"content,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Dt        float64                   `protobuf:"fixed64,3,opt,name=dt,proto3" json:"dt,omitempty"`                           // Step size every body must use for this round
+	Time      float64                   `protobuf:"fixed64,4,opt,name=time,proto3" json:"time,omitempty"`                       // Simulation time of the snapshot
+	Round     uint64                    `protobuf:"varint,5,opt,name=round,proto3" json:"round,omitempty"`                      // Index of the round, starting at 0
+	Mergers   []*Merger                 `protobuf:"bytes,6,rep,name=mergers,proto3" json:"mergers,omitempty"`                   // Collisions resolved before this round
+	Units     Units                     `protobuf:"varint,7,opt,name=units,proto3,enum=taiyoukei.Units" json:"units,omitempty"` // Unit system of the simulation
+	Frame     string                    `protobuf:"bytes,8,opt,name=frame,proto3" json:"frame,omitempty"`                       // Reference frame of the states, see CelestialBodiesPositionRequest
+	Dropped   uint64                    `protobuf:"varint,9,opt,name=dropped,proto3" json:"dropped,omitempty"`                  // Snapshots dropped for this subscriber since it subscribed
+	Shutdown  bool                      `protobuf:"varint,10,opt,name=shutdown,proto3" json:"shutdown,omitempty"`               // The simulation is over and the stream ends, no round follows
+	Impulses  []*Impulse                `protobuf:"bytes,11,rep,name=impulses,proto3" json:"impulses,omitempty"`                // To apply to the states of the content before integrating them
+	Scheduled []*Impulse                `protobuf:"bytes,12,rep,name=scheduled,proto3" json:"scheduled,omitempty"`              // Impulses not due yet, only set in checkpoints
+	Removed   []string                  `protobuf:"bytes,13,rep,name=removed,proto3" json:"removed,omitempty"`                  // Bodies removed without a merger: invalid states, and the group of a removed or absorbed body
+}
+
+func (x *Data) Reset() {
+	*x = Data{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_celestial_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Data) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data) ProtoMessage() {}
+
+func (x *Data) ProtoReflect() protoreflect.Message {
+	mi := &file_celestial_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data.ProtoReflect.Descriptor instead.
+func (*Data) Descriptor() ([]byte, []int) {
+	return file_celestial_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Data) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *Data) GetContent() map[string]*CelestialBody {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+func (x *Data) GetDt() float64 {
+	if x != nil {
+		return x.Dt
+	}
+	return 0
+}
+
+func (x *Data) GetTime() float64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *Data) GetRound() uint64 {
+	if x != nil {
+		return x.Round
+	}
+	return 0
+}
+
+func (x *Data) GetMergers() []*Merger {
+	if x != nil {
+		return x.Mergers
+	}
+	return nil
+}
+
+func (x *Data) GetUnits() Units {
+	if x != nil {
+		return x.Units
+	}
+	return Units_CANONICAL
+}
+
+func (x *Data) GetFrame() string {
+	if x != nil {
+		return x.Frame
+	}
+	return ""
+}
+
+func (x *Data) GetDropped() uint64 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+func (x *Data) GetShutdown() bool {
+	if x != nil {
+		return x.Shutdown
+	}
+	return false
+}
+
+func (x *Data) GetImpulses() []*Impulse {
+	if x != nil {
+		return x.Impulses
+	}
+	return nil
+}
+
+func (x *Data) GetScheduled() []*Impulse {
+	if x != nil {
+		return x.Scheduled
+	}
+	return nil
+}
+
+func (x *Data) GetRemoved() []string {
+	if x != nil {
+		return x.Removed
+	}
+	return nil
+}
+
+// Instantaneous change of velocity of a body, e.g. a maneuver. The server forwards it in
+// the broadcast of the round it is due, and every client applies it to the broadcast state
+// of the body before integrating the round.
+type Impulse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name     string  `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Dvx      float64 `protobuf:"fixed64,2,opt,name=dvx,proto3" json:"dvx,omitempty"`
+	Dvy      float64 `protobuf:"fixed64,3,opt,name=dvy,proto3" json:"dvy,omitempty"`
+	Dvz      float64 `protobuf:"fixed64,4,opt,name=dvz,proto3" json:"dvz,omitempty"`
+	Sequence uint64  `protobuf:"varint,5,opt,name=sequence,proto3" json:"sequence,omitempty"` // Due in the round the body reports this sequence or a later one
+	Time     float64 `protobuf:"fixed64,6,opt,name=time,proto3" json:"time,omitempty"`        // Due in the first round at or after this simulation time, when sequence is 0
 }
 
-func (x *CelestialBody) Reset() {
-	*x = CelestialBody{}
+func (x *Impulse) Reset() {
+	*x = Impulse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_celestial_proto_msgTypes[0]
+		mi := &file_celestial_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *CelestialBody) String() string {
+func (x *Impulse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CelestialBody) ProtoMessage() {}
+func (*Impulse) ProtoMessage() {}
 
-func (x *CelestialBody) ProtoReflect() protoreflect.Message {
-	mi := &file_celestial_proto_msgTypes[0]
+func (x *Impulse) ProtoReflect() protoreflect.Message {
+	mi := &file_celestial_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -61,83 +714,133 @@ func (x *CelestialBody) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CelestialBody.ProtoReflect.Descriptor instead.
-func (*CelestialBody) Descriptor() ([]byte, []int) {
-	return file_celestial_proto_rawDescGZIP(), []int{0}
+// Deprecated: Use Impulse.ProtoReflect.Descriptor instead.
+func (*Impulse) Descriptor() ([]byte, []int) {
+	return file_celestial_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *CelestialBody) GetSequence() uint64 {
+func (x *Impulse) GetName() string {
 	if x != nil {
-		return x.Sequence
+		return x.Name
 	}
-	return 0
+	return ""
 }
 
-func (x *CelestialBody) GetName() string {
+func (x *Impulse) GetDvx() float64 {
 	if x != nil {
-		return x.Name
+		return x.Dvx
 	}
-	return ""
+	return 0
 }
 
-func (x *CelestialBody) GetMass() float64 {
+func (x *Impulse) GetDvy() float64 {
 	if x != nil {
-		return x.Mass
+		return x.Dvy
 	}
 	return 0
 }
 
-func (x *CelestialBody) GetX() float64 {
+func (x *Impulse) GetDvz() float64 {
 	if x != nil {
-		return x.X
+		return x.Dvz
 	}
 	return 0
 }
 
-func (x *CelestialBody) GetY() float64 {
+func (x *Impulse) GetSequence() uint64 {
 	if x != nil {
-		return x.Y
+		return x.Sequence
 	}
 	return 0
 }
 
-func (x *CelestialBody) GetVx() float64 {
+func (x *Impulse) GetTime() float64 {
 	if x != nil {
-		return x.Vx
+		return x.Time
 	}
 	return 0
 }
 
-func (x *CelestialBody) GetVy() float64 {
+// Inelastic merger of two colliding bodies: the survivor carries on with the merged
+// state while the absorbed body leaves the simulation
+type Merger struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Absorbed string `protobuf:"bytes,1,opt,name=absorbed,proto3" json:"absorbed,omitempty"`
+	Survivor string `protobuf:"bytes,2,opt,name=survivor,proto3" json:"survivor,omitempty"`
+}
+
+func (x *Merger) Reset() {
+	*x = Merger{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_celestial_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Merger) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Merger) ProtoMessage() {}
+
+func (x *Merger) ProtoReflect() protoreflect.Message {
+	mi := &file_celestial_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Merger.ProtoReflect.Descriptor instead.
+func (*Merger) Descriptor() ([]byte, []int) {
+	return file_celestial_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Merger) GetAbsorbed() string {
 	if x != nil {
-		return x.Vy
+		return x.Absorbed
 	}
-	return 0
+	return ""
 }
 
-type CelestialBodiesPositionRequest struct {
+func (x *Merger) GetSurvivor() string {
+	if x != nil {
+		return x.Survivor
+	}
+	return ""
+}
+
+type ControlRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 }
 
-func (x *CelestialBodiesPositionRequest) Reset() {
-	*x = CelestialBodiesPositionRequest{}
+func (x *ControlRequest) Reset() {
+	*x = ControlRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_celestial_proto_msgTypes[1]
+		mi := &file_celestial_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *CelestialBodiesPositionRequest) String() string {
+func (x *ControlRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CelestialBodiesPositionRequest) ProtoMessage() {}
+func (*ControlRequest) ProtoMessage() {}
 
-func (x *CelestialBodiesPositionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_celestial_proto_msgTypes[1]
+func (x *ControlRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_celestial_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -148,37 +851,36 @@ func (x *CelestialBodiesPositionRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CelestialBodiesPositionRequest.ProtoReflect.Descriptor instead.
-func (*CelestialBodiesPositionRequest) Descriptor() ([]byte, []int) {
-	return file_celestial_proto_rawDescGZIP(), []int{1}
+// Deprecated: Use ControlRequest.ProtoReflect.Descriptor instead.
+func (*ControlRequest) Descriptor() ([]byte, []int) {
+	return file_celestial_proto_rawDescGZIP(), []int{8}
 }
 
-type Data struct {
+type StepRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Success bool                      `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Content map[string]*CelestialBody `protobuf:"bytes,2,rep,name=content,proto3" json:"content,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	N uint64 `protobuf:"varint,1,opt,name=n,proto3" json:"n,omitempty"`
 }
 
-func (x *Data) Reset() {
-	*x = Data{}
+func (x *StepRequest) Reset() {
+	*x = StepRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_celestial_proto_msgTypes[2]
+		mi := &file_celestial_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Data) String() string {
+func (x *StepRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Data) ProtoMessage() {}
+func (*StepRequest) ProtoMessage() {}
 
-func (x *Data) ProtoReflect() protoreflect.Message {
-	mi := &file_celestial_proto_msgTypes[2]
+func (x *StepRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_celestial_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -189,30 +891,94 @@ func (x *Data) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Data.ProtoReflect.Descriptor instead.
-func (*Data) Descriptor() ([]byte, []int) {
-	return file_celestial_proto_rawDescGZIP(), []int{2}
+// Deprecated: Use StepRequest.ProtoReflect.Descriptor instead.
+func (*StepRequest) Descriptor() ([]byte, []int) {
+	return file_celestial_proto_rawDescGZIP(), []int{9}
 }
 
-func (x *Data) GetSuccess() bool {
+func (x *StepRequest) GetN() uint64 {
 	if x != nil {
-		return x.Success
+		return x.N
+	}
+	return 0
+}
+
+type ControlReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Round    uint64 `protobuf:"varint,1,opt,name=round,proto3" json:"round,omitempty"` // Next round to be broadcast
+	Paused   bool   `protobuf:"varint,2,opt,name=paused,proto3" json:"paused,omitempty"`
+	Steps    uint64 `protobuf:"varint,3,opt,name=steps,proto3" json:"steps,omitempty"`       // Rounds still to be broadcast before pausing
+	Impulses uint64 `protobuf:"varint,4,opt,name=impulses,proto3" json:"impulses,omitempty"` // Impulses scheduled that are not due yet
+}
+
+func (x *ControlReply) Reset() {
+	*x = ControlReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_celestial_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ControlReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlReply) ProtoMessage() {}
+
+func (x *ControlReply) ProtoReflect() protoreflect.Message {
+	mi := &file_celestial_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ControlReply.ProtoReflect.Descriptor instead.
+func (*ControlReply) Descriptor() ([]byte, []int) {
+	return file_celestial_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ControlReply) GetRound() uint64 {
+	if x != nil {
+		return x.Round
+	}
+	return 0
+}
+
+func (x *ControlReply) GetPaused() bool {
+	if x != nil {
+		return x.Paused
 	}
 	return false
 }
 
-func (x *Data) GetContent() map[string]*CelestialBody {
+func (x *ControlReply) GetSteps() uint64 {
 	if x != nil {
-		return x.Content
+		return x.Steps
 	}
-	return nil
+	return 0
+}
+
+func (x *ControlReply) GetImpulses() uint64 {
+	if x != nil {
+		return x.Impulses
+	}
+	return 0
 }
 
 var File_celestial_proto protoreflect.FileDescriptor
 
 var file_celestial_proto_rawDesc = []byte{
 	0x0a, 0x0f, 0x63, 0x65, 0x6c, 0x65, 0x73, 0x74, 0x69, 0x61, 0x6c, 0x2e, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x12, 0x09, 0x74, 0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x22, 0x8f, 0x01, 0x0a,
+	0x6f, 0x12, 0x09, 0x74, 0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x22, 0xa7, 0x03, 0x0a,
 	0x0d, 0x43, 0x65, 0x6c, 0x65, 0x73, 0x74, 0x69, 0x61, 0x6c, 0x42, 0x6f, 0x64, 0x79, 0x12, 0x1a,
 	0x0a, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04,
 	0x52, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
@@ -221,33 +987,156 @@ var file_celestial_proto_rawDesc = []byte{
 	0x73, 0x73, 0x12, 0x0c, 0x0a, 0x01, 0x78, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x01, 0x78,
 	0x12, 0x0c, 0x0a, 0x01, 0x79, 0x18, 0x05, 0x20, 0x01, 0x28, 0x01, 0x52, 0x01, 0x79, 0x12, 0x0e,
 	0x0a, 0x02, 0x76, 0x78, 0x18, 0x06, 0x20, 0x01, 0x28, 0x01, 0x52, 0x02, 0x76, 0x78, 0x12, 0x0e,
-	0x0a, 0x02, 0x76, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28, 0x01, 0x52, 0x02, 0x76, 0x79, 0x22, 0x20,
-	0x0a, 0x1e, 0x43, 0x65, 0x6c, 0x65, 0x73, 0x74, 0x69, 0x61, 0x6c, 0x42, 0x6f, 0x64, 0x69, 0x65,
-	0x73, 0x50, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
-	0x22, 0xae, 0x01, 0x0a, 0x04, 0x44, 0x61, 0x74, 0x61, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x63,
-	0x63, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x73, 0x75, 0x63, 0x63,
-	0x65, 0x73, 0x73, 0x12, 0x36, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18, 0x02,
-	0x20, 0x03, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x74, 0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69,
-	0x2e, 0x44, 0x61, 0x74, 0x61, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x45, 0x6e, 0x74,
-	0x72, 0x79, 0x52, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x1a, 0x54, 0x0a, 0x0c, 0x43,
-	0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b,
-	0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x2e, 0x0a,
-	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x74,
-	0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e, 0x43, 0x65, 0x6c, 0x65, 0x73, 0x74, 0x69,
-	0x61, 0x6c, 0x42, 0x6f, 0x64, 0x79, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38,
-	0x01, 0x32, 0xb2, 0x01, 0x0a, 0x10, 0x43, 0x65, 0x6c, 0x65, 0x73, 0x74, 0x69, 0x61, 0x6c, 0x53,
-	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x42, 0x0a, 0x0f, 0x43, 0x65, 0x6c, 0x65, 0x73, 0x74,
-	0x69, 0x61, 0x6c, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x12, 0x18, 0x2e, 0x74, 0x61, 0x69, 0x79,
+	0x0a, 0x02, 0x76, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28, 0x01, 0x52, 0x02, 0x76, 0x79, 0x12, 0x0c,
+	0x0a, 0x01, 0x7a, 0x18, 0x08, 0x20, 0x01, 0x28, 0x01, 0x52, 0x01, 0x7a, 0x12, 0x0e, 0x0a, 0x02,
+	0x76, 0x7a, 0x18, 0x09, 0x20, 0x01, 0x28, 0x01, 0x52, 0x02, 0x76, 0x7a, 0x12, 0x0e, 0x0a, 0x02,
+	0x64, 0x74, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x01, 0x52, 0x02, 0x64, 0x74, 0x12, 0x1d, 0x0a, 0x0a,
+	0x6a, 0x6f, 0x69, 0x6e, 0x5f, 0x72, 0x6f, 0x75, 0x6e, 0x64, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x09, 0x6a, 0x6f, 0x69, 0x6e, 0x52, 0x6f, 0x75, 0x6e, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x72,
+	0x61, 0x64, 0x69, 0x75, 0x73, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x01, 0x52, 0x06, 0x72, 0x61, 0x64,
+	0x69, 0x75, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x72, 0x18, 0x0d, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x72, 0x12, 0x26, 0x0a, 0x05, 0x75,
+	0x6e, 0x69, 0x74, 0x73, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x10, 0x2e, 0x74, 0x61, 0x69,
+	0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e, 0x55, 0x6e, 0x69, 0x74, 0x73, 0x52, 0x05, 0x75, 0x6e,
+	0x69, 0x74, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x74, 0x65, 0x73, 0x74, 0x5f, 0x70, 0x61, 0x72, 0x74,
+	0x69, 0x63, 0x6c, 0x65, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x74, 0x65, 0x73, 0x74,
+	0x50, 0x61, 0x72, 0x74, 0x69, 0x63, 0x6c, 0x65, 0x12, 0x2e, 0x0a, 0x05, 0x67, 0x72, 0x6f, 0x75,
+	0x70, 0x18, 0x10, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x74, 0x61, 0x69, 0x79, 0x6f, 0x75,
+	0x6b, 0x65, 0x69, 0x2e, 0x43, 0x65, 0x6c, 0x65, 0x73, 0x74, 0x69, 0x61, 0x6c, 0x42, 0x6f, 0x64,
+	0x79, 0x52, 0x05, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x68, 0x72, 0x75,
+	0x73, 0x74, 0x69, 0x6e, 0x67, 0x18, 0x11, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x74, 0x68, 0x72,
+	0x75, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x22, 0xa0, 0x01, 0x0a, 0x1e, 0x43, 0x65, 0x6c, 0x65, 0x73,
+	0x74, 0x69, 0x61, 0x6c, 0x42, 0x6f, 0x64, 0x69, 0x65, 0x73, 0x50, 0x6f, 0x73, 0x69, 0x74, 0x69,
+	0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x61, 0x6d,
+	0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x12,
+	0x1e, 0x0a, 0x0a, 0x64, 0x65, 0x63, 0x69, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x0a, 0x64, 0x65, 0x63, 0x69, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x14, 0x0a, 0x05, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x66, 0x72, 0x61, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x62, 0x75, 0x66, 0x66, 0x65, 0x72, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x62, 0x75, 0x66, 0x66, 0x65, 0x72, 0x12, 0x1a, 0x0a,
+	0x08, 0x6c, 0x6f, 0x73, 0x73, 0x6c, 0x65, 0x73, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x08, 0x6c, 0x6f, 0x73, 0x73, 0x6c, 0x65, 0x73, 0x73, 0x22, 0xd0, 0x01, 0x0a, 0x0e, 0x48, 0x69,
+	0x73, 0x74, 0x6f, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05,
+	0x6e, 0x61, 0x6d, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x6e, 0x61, 0x6d,
+	0x65, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x66, 0x72, 0x6f, 0x6d, 0x5f, 0x72, 0x6f, 0x75, 0x6e, 0x64,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x66, 0x72, 0x6f, 0x6d, 0x52, 0x6f, 0x75, 0x6e,
+	0x64, 0x12, 0x19, 0x0a, 0x08, 0x74, 0x6f, 0x5f, 0x72, 0x6f, 0x75, 0x6e, 0x64, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x07, 0x74, 0x6f, 0x52, 0x6f, 0x75, 0x6e, 0x64, 0x12, 0x20, 0x0a, 0x09,
+	0x66, 0x72, 0x6f, 0x6d, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x48,
+	0x00, 0x52, 0x08, 0x66, 0x72, 0x6f, 0x6d, 0x54, 0x69, 0x6d, 0x65, 0x88, 0x01, 0x01, 0x12, 0x1c,
+	0x0a, 0x07, 0x74, 0x6f, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x01, 0x48,
+	0x01, 0x52, 0x06, 0x74, 0x6f, 0x54, 0x69, 0x6d, 0x65, 0x88, 0x01, 0x01, 0x12, 0x14, 0x0a, 0x05,
+	0x66, 0x72, 0x61, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x66, 0x72, 0x61,
+	0x6d, 0x65, 0x42, 0x0c, 0x0a, 0x0a, 0x5f, 0x66, 0x72, 0x6f, 0x6d, 0x5f, 0x74, 0x69, 0x6d, 0x65,
+	0x42, 0x0a, 0x0a, 0x08, 0x5f, 0x74, 0x6f, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x22, 0x27, 0x0a, 0x11,
+	0x43, 0x68, 0x65, 0x63, 0x6b, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x70, 0x61, 0x74, 0x68, 0x22, 0x4f, 0x0a, 0x0f, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x70, 0x6f,
+	0x69, 0x6e, 0x74, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x12, 0x14, 0x0a, 0x05,
+	0x72, 0x6f, 0x75, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x72, 0x6f, 0x75,
+	0x6e, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01,
+	0x52, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x22, 0x85, 0x04, 0x0a, 0x04, 0x44, 0x61, 0x74, 0x61, 0x12,
+	0x18, 0x0a, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x12, 0x36, 0x0a, 0x07, 0x63, 0x6f, 0x6e,
+	0x74, 0x65, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x74, 0x61, 0x69,
+	0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x2e, 0x43, 0x6f, 0x6e, 0x74,
+	0x65, 0x6e, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e,
+	0x74, 0x12, 0x0e, 0x0a, 0x02, 0x64, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x02, 0x64,
+	0x74, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x04, 0x74, 0x69, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x6f, 0x75, 0x6e, 0x64, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x72, 0x6f, 0x75, 0x6e, 0x64, 0x12, 0x2b, 0x0a, 0x07, 0x6d,
+	0x65, 0x72, 0x67, 0x65, 0x72, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x74,
+	0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e, 0x4d, 0x65, 0x72, 0x67, 0x65, 0x72, 0x52,
+	0x07, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x72, 0x73, 0x12, 0x26, 0x0a, 0x05, 0x75, 0x6e, 0x69, 0x74,
+	0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x10, 0x2e, 0x74, 0x61, 0x69, 0x79, 0x6f, 0x75,
+	0x6b, 0x65, 0x69, 0x2e, 0x55, 0x6e, 0x69, 0x74, 0x73, 0x52, 0x05, 0x75, 0x6e, 0x69, 0x74, 0x73,
+	0x12, 0x14, 0x0a, 0x05, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x72, 0x6f, 0x70, 0x70, 0x65,
+	0x64, 0x18, 0x09, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x64, 0x72, 0x6f, 0x70, 0x70, 0x65, 0x64,
+	0x12, 0x1a, 0x0a, 0x08, 0x73, 0x68, 0x75, 0x74, 0x64, 0x6f, 0x77, 0x6e, 0x18, 0x0a, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x08, 0x73, 0x68, 0x75, 0x74, 0x64, 0x6f, 0x77, 0x6e, 0x12, 0x2e, 0x0a, 0x08,
+	0x69, 0x6d, 0x70, 0x75, 0x6c, 0x73, 0x65, 0x73, 0x18, 0x0b, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12,
+	0x2e, 0x74, 0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e, 0x49, 0x6d, 0x70, 0x75, 0x6c,
+	0x73, 0x65, 0x52, 0x08, 0x69, 0x6d, 0x70, 0x75, 0x6c, 0x73, 0x65, 0x73, 0x12, 0x30, 0x0a, 0x09,
+	0x73, 0x63, 0x68, 0x65, 0x64, 0x75, 0x6c, 0x65, 0x64, 0x18, 0x0c, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x12, 0x2e, 0x74, 0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e, 0x49, 0x6d, 0x70, 0x75,
+	0x6c, 0x73, 0x65, 0x52, 0x09, 0x73, 0x63, 0x68, 0x65, 0x64, 0x75, 0x6c, 0x65, 0x64, 0x12, 0x18,
+	0x0a, 0x07, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x18, 0x0d, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x07, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x1a, 0x54, 0x0a, 0x0c, 0x43, 0x6f, 0x6e, 0x74,
+	0x65, 0x6e, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x2e, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x74, 0x61, 0x69, 0x79,
 	0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e, 0x43, 0x65, 0x6c, 0x65, 0x73, 0x74, 0x69, 0x61, 0x6c, 0x42,
-	0x6f, 0x64, 0x79, 0x1a, 0x0f, 0x2e, 0x74, 0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e,
-	0x44, 0x61, 0x74, 0x61, 0x22, 0x00, 0x28, 0x01, 0x30, 0x01, 0x12, 0x5a, 0x0a, 0x18, 0x43, 0x65,
-	0x6c, 0x65, 0x73, 0x74, 0x69, 0x61, 0x6c, 0x42, 0x6f, 0x64, 0x69, 0x65, 0x73, 0x50, 0x6f, 0x73,
-	0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x29, 0x2e, 0x74, 0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b,
-	0x65, 0x69, 0x2e, 0x43, 0x65, 0x6c, 0x65, 0x73, 0x74, 0x69, 0x61, 0x6c, 0x42, 0x6f, 0x64, 0x69,
-	0x65, 0x73, 0x50, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x1a, 0x0f, 0x2e, 0x74, 0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e, 0x44, 0x61,
-	0x74, 0x61, 0x22, 0x00, 0x30, 0x01, 0x42, 0x09, 0x5a, 0x07, 0x2e, 0x2f, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x6f, 0x64, 0x79, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x83,
+	0x01, 0x0a, 0x07, 0x49, 0x6d, 0x70, 0x75, 0x6c, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x10,
+	0x0a, 0x03, 0x64, 0x76, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x64, 0x76, 0x78,
+	0x12, 0x10, 0x0a, 0x03, 0x64, 0x76, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x64,
+	0x76, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x64, 0x76, 0x7a, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x03, 0x64, 0x76, 0x7a, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65,
+	0x12, 0x12, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x01, 0x52, 0x04,
+	0x74, 0x69, 0x6d, 0x65, 0x22, 0x40, 0x0a, 0x06, 0x4d, 0x65, 0x72, 0x67, 0x65, 0x72, 0x12, 0x1a,
+	0x0a, 0x08, 0x61, 0x62, 0x73, 0x6f, 0x72, 0x62, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x08, 0x61, 0x62, 0x73, 0x6f, 0x72, 0x62, 0x65, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x75,
+	0x72, 0x76, 0x69, 0x76, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x73, 0x75,
+	0x72, 0x76, 0x69, 0x76, 0x6f, 0x72, 0x22, 0x10, 0x0a, 0x0e, 0x43, 0x6f, 0x6e, 0x74, 0x72, 0x6f,
+	0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x1b, 0x0a, 0x0b, 0x53, 0x74, 0x65, 0x70,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0c, 0x0a, 0x01, 0x6e, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x01, 0x6e, 0x22, 0x6e, 0x0a, 0x0c, 0x43, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c,
+	0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x6f, 0x75, 0x6e, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x72, 0x6f, 0x75, 0x6e, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x70,
+	0x61, 0x75, 0x73, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x70, 0x61, 0x75,
+	0x73, 0x65, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x65, 0x70, 0x73, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x05, 0x73, 0x74, 0x65, 0x70, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x69, 0x6d, 0x70,
+	0x75, 0x6c, 0x73, 0x65, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x69, 0x6d, 0x70,
+	0x75, 0x6c, 0x73, 0x65, 0x73, 0x2a, 0x30, 0x0a, 0x05, 0x55, 0x6e, 0x69, 0x74, 0x73, 0x12, 0x0d,
+	0x0a, 0x09, 0x43, 0x41, 0x4e, 0x4f, 0x4e, 0x49, 0x43, 0x41, 0x4c, 0x10, 0x00, 0x12, 0x06, 0x0a,
+	0x02, 0x53, 0x49, 0x10, 0x01, 0x12, 0x10, 0x0a, 0x0c, 0x41, 0x53, 0x54, 0x52, 0x4f, 0x4e, 0x4f,
+	0x4d, 0x49, 0x43, 0x41, 0x4c, 0x10, 0x02, 0x32, 0xb7, 0x02, 0x0a, 0x10, 0x43, 0x65, 0x6c, 0x65,
+	0x73, 0x74, 0x69, 0x61, 0x6c, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x42, 0x0a, 0x0f,
+	0x43, 0x65, 0x6c, 0x65, 0x73, 0x74, 0x69, 0x61, 0x6c, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x12,
+	0x18, 0x2e, 0x74, 0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e, 0x43, 0x65, 0x6c, 0x65,
+	0x73, 0x74, 0x69, 0x61, 0x6c, 0x42, 0x6f, 0x64, 0x79, 0x1a, 0x0f, 0x2e, 0x74, 0x61, 0x69, 0x79,
+	0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x22, 0x00, 0x28, 0x01, 0x30, 0x01,
+	0x12, 0x5a, 0x0a, 0x18, 0x43, 0x65, 0x6c, 0x65, 0x73, 0x74, 0x69, 0x61, 0x6c, 0x42, 0x6f, 0x64,
+	0x69, 0x65, 0x73, 0x50, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x29, 0x2e, 0x74,
+	0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e, 0x43, 0x65, 0x6c, 0x65, 0x73, 0x74, 0x69,
+	0x61, 0x6c, 0x42, 0x6f, 0x64, 0x69, 0x65, 0x73, 0x50, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0f, 0x2e, 0x74, 0x61, 0x69, 0x79, 0x6f, 0x75,
+	0x6b, 0x65, 0x69, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x22, 0x00, 0x30, 0x01, 0x12, 0x48, 0x0a, 0x0a,
+	0x43, 0x68, 0x65, 0x63, 0x6b, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x1c, 0x2e, 0x74, 0x61, 0x69,
+	0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x70, 0x6f, 0x69, 0x6e,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x74, 0x61, 0x69, 0x79, 0x6f,
+	0x75, 0x6b, 0x65, 0x69, 0x2e, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x52,
+	0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x39, 0x0a, 0x07, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72,
+	0x79, 0x12, 0x19, 0x2e, 0x74, 0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e, 0x48, 0x69,
+	0x73, 0x74, 0x6f, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0f, 0x2e, 0x74,
+	0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x22, 0x00, 0x30,
+	0x01, 0x32, 0xcf, 0x02, 0x0a, 0x0e, 0x43, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x53, 0x65, 0x72,
+	0x76, 0x69, 0x63, 0x65, 0x12, 0x3d, 0x0a, 0x05, 0x50, 0x61, 0x75, 0x73, 0x65, 0x12, 0x19, 0x2e,
+	0x74, 0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x72, 0x6f,
+	0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x74, 0x61, 0x69, 0x79, 0x6f,
+	0x75, 0x6b, 0x65, 0x69, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x52, 0x65, 0x70, 0x6c,
+	0x79, 0x22, 0x00, 0x12, 0x3e, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x12, 0x19, 0x2e,
+	0x74, 0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x72, 0x6f,
+	0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x74, 0x61, 0x69, 0x79, 0x6f,
+	0x75, 0x6b, 0x65, 0x69, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x52, 0x65, 0x70, 0x6c,
+	0x79, 0x22, 0x00, 0x12, 0x3a, 0x0a, 0x05, 0x53, 0x74, 0x65, 0x70, 0x4e, 0x12, 0x16, 0x2e, 0x74,
+	0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e, 0x53, 0x74, 0x65, 0x70, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x74, 0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69,
+	0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12,
+	0x40, 0x0a, 0x08, 0x53, 0x68, 0x75, 0x74, 0x64, 0x6f, 0x77, 0x6e, 0x12, 0x19, 0x2e, 0x74, 0x61,
+	0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x74, 0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b,
+	0x65, 0x69, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22,
+	0x00, 0x12, 0x40, 0x0a, 0x0f, 0x53, 0x63, 0x68, 0x65, 0x64, 0x75, 0x6c, 0x65, 0x49, 0x6d, 0x70,
+	0x75, 0x6c, 0x73, 0x65, 0x12, 0x12, 0x2e, 0x74, 0x61, 0x69, 0x79, 0x6f, 0x75, 0x6b, 0x65, 0x69,
+	0x2e, 0x49, 0x6d, 0x70, 0x75, 0x6c, 0x73, 0x65, 0x1a, 0x17, 0x2e, 0x74, 0x61, 0x69, 0x79, 0x6f,
+	0x75, 0x6b, 0x65, 0x69, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x52, 0x65, 0x70, 0x6c,
+	0x79, 0x22, 0x00, 0x42, 0x09, 0x5a, 0x07, 0x2e, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -262,25 +1151,55 @@ func file_celestial_proto_rawDescGZIP() []byte {
 	return file_celestial_proto_rawDescData
 }
 
-var file_celestial_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_celestial_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_celestial_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
 var file_celestial_proto_goTypes = []interface{}{
-	(*CelestialBody)(nil),                  // 0: taiyoukei.CelestialBody
-	(*CelestialBodiesPositionRequest)(nil), // 1: taiyoukei.CelestialBodiesPositionRequest
-	(*Data)(nil),                           // 2: taiyoukei.Data
-	nil,                                    // 3: taiyoukei.Data.ContentEntry
+	(Units)(0),                             // 0: taiyoukei.Units
+	(*CelestialBody)(nil),                  // 1: taiyoukei.CelestialBody
+	(*CelestialBodiesPositionRequest)(nil), // 2: taiyoukei.CelestialBodiesPositionRequest
+	(*HistoryRequest)(nil),                 // 3: taiyoukei.HistoryRequest
+	(*CheckpointRequest)(nil),              // 4: taiyoukei.CheckpointRequest
+	(*CheckpointReply)(nil),                // 5: taiyoukei.CheckpointReply
+	(*Data)(nil),                           // 6: taiyoukei.Data
+	(*Impulse)(nil),                        // 7: taiyoukei.Impulse
+	(*Merger)(nil),                         // 8: taiyoukei.Merger
+	(*ControlRequest)(nil),                 // 9: taiyoukei.ControlRequest
+	(*StepRequest)(nil),                    // 10: taiyoukei.StepRequest
+	(*ControlReply)(nil),                   // 11: taiyoukei.ControlReply
+	nil,                                    // 12: taiyoukei.Data.ContentEntry
 }
 var file_celestial_proto_depIdxs = []int32{
-	3, // 0: taiyoukei.Data.content:type_name -> taiyoukei.Data.ContentEntry
-	0, // 1: taiyoukei.Data.ContentEntry.value:type_name -> taiyoukei.CelestialBody
-	0, // 2: taiyoukei.CelestialService.CelestialUpdate:input_type -> taiyoukei.CelestialBody
-	1, // 3: taiyoukei.CelestialService.CelestialBodiesPositions:input_type -> taiyoukei.CelestialBodiesPositionRequest
-	2, // 4: taiyoukei.CelestialService.CelestialUpdate:output_type -> taiyoukei.Data
-	2, // 5: taiyoukei.CelestialService.CelestialBodiesPositions:output_type -> taiyoukei.Data
-	4, // [4:6] is the sub-list for method output_type
-	2, // [2:4] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	0,  // 0: taiyoukei.CelestialBody.units:type_name -> taiyoukei.Units
+	1,  // 1: taiyoukei.CelestialBody.group:type_name -> taiyoukei.CelestialBody
+	12, // 2: taiyoukei.Data.content:type_name -> taiyoukei.Data.ContentEntry
+	8,  // 3: taiyoukei.Data.mergers:type_name -> taiyoukei.Merger
+	0,  // 4: taiyoukei.Data.units:type_name -> taiyoukei.Units
+	7,  // 5: taiyoukei.Data.impulses:type_name -> taiyoukei.Impulse
+	7,  // 6: taiyoukei.Data.scheduled:type_name -> taiyoukei.Impulse
+	1,  // 7: taiyoukei.Data.ContentEntry.value:type_name -> taiyoukei.CelestialBody
+	1,  // 8: taiyoukei.CelestialService.CelestialUpdate:input_type -> taiyoukei.CelestialBody
+	2,  // 9: taiyoukei.CelestialService.CelestialBodiesPositions:input_type -> taiyoukei.CelestialBodiesPositionRequest
+	4,  // 10: taiyoukei.CelestialService.Checkpoint:input_type -> taiyoukei.CheckpointRequest
+	3,  // 11: taiyoukei.CelestialService.History:input_type -> taiyoukei.HistoryRequest
+	9,  // 12: taiyoukei.ControlService.Pause:input_type -> taiyoukei.ControlRequest
+	9,  // 13: taiyoukei.ControlService.Resume:input_type -> taiyoukei.ControlRequest
+	10, // 14: taiyoukei.ControlService.StepN:input_type -> taiyoukei.StepRequest
+	9,  // 15: taiyoukei.ControlService.Shutdown:input_type -> taiyoukei.ControlRequest
+	7,  // 16: taiyoukei.ControlService.ScheduleImpulse:input_type -> taiyoukei.Impulse
+	6,  // 17: taiyoukei.CelestialService.CelestialUpdate:output_type -> taiyoukei.Data
+	6,  // 18: taiyoukei.CelestialService.CelestialBodiesPositions:output_type -> taiyoukei.Data
+	5,  // 19: taiyoukei.CelestialService.Checkpoint:output_type -> taiyoukei.CheckpointReply
+	6,  // 20: taiyoukei.CelestialService.History:output_type -> taiyoukei.Data
+	11, // 21: taiyoukei.ControlService.Pause:output_type -> taiyoukei.ControlReply
+	11, // 22: taiyoukei.ControlService.Resume:output_type -> taiyoukei.ControlReply
+	11, // 23: taiyoukei.ControlService.StepN:output_type -> taiyoukei.ControlReply
+	11, // 24: taiyoukei.ControlService.Shutdown:output_type -> taiyoukei.ControlReply
+	11, // 25: taiyoukei.ControlService.ScheduleImpulse:output_type -> taiyoukei.ControlReply
+	17, // [17:26] is the sub-list for method output_type
+	8,  // [8:17] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_celestial_proto_init() }
@@ -314,6 +1233,42 @@ func file_celestial_proto_init() {
 			}
 		}
 		file_celestial_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HistoryRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_celestial_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CheckpointRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_celestial_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CheckpointReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_celestial_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Data); i {
 			case 0:
 				return &v.state
@@ -325,19 +1280,81 @@ func file_celestial_proto_init() {
 				return nil
 			}
 		}
+		file_celestial_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Impulse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_celestial_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Merger); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_celestial_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ControlRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_celestial_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StepRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_celestial_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ControlReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
+	file_celestial_proto_msgTypes[2].OneofWrappers = []interface{}{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_celestial_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   4,
+			NumEnums:      1,
+			NumMessages:   12,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_celestial_proto_goTypes,
 		DependencyIndexes: file_celestial_proto_depIdxs,
+		EnumInfos:         file_celestial_proto_enumTypes,
 		MessageInfos:      file_celestial_proto_msgTypes,
 	}.Build()
 	File_celestial_proto = out.File
@@ -21,6 +21,8 @@ const _ = grpc.SupportPackageIsVersion7
 const (
 	CelestialService_CelestialUpdate_FullMethodName          = "/taiyoukei.CelestialService/CelestialUpdate"
 	CelestialService_CelestialBodiesPositions_FullMethodName = "/taiyoukei.CelestialService/CelestialBodiesPositions"
+	CelestialService_Checkpoint_FullMethodName               = "/taiyoukei.CelestialService/Checkpoint"
+	CelestialService_History_FullMethodName                  = "/taiyoukei.CelestialService/History"
 )
 
 // CelestialServiceClient is the client API for CelestialService service.
@@ -29,6 +31,8 @@ const (
 type CelestialServiceClient interface {
 	CelestialUpdate(ctx context.Context, opts ...grpc.CallOption) (CelestialService_CelestialUpdateClient, error)
 	CelestialBodiesPositions(ctx context.Context, in *CelestialBodiesPositionRequest, opts ...grpc.CallOption) (CelestialService_CelestialBodiesPositionsClient, error)
+	Checkpoint(ctx context.Context, in *CheckpointRequest, opts ...grpc.CallOption) (*CheckpointReply, error)
+	History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (CelestialService_HistoryClient, error)
 }
 
 type celestialServiceClient struct {
@@ -102,12 +106,55 @@ func (x *celestialServiceCelestialBodiesPositionsClient) Recv() (*Data, error) {
 	return m, nil
 }
 
+func (c *celestialServiceClient) Checkpoint(ctx context.Context, in *CheckpointRequest, opts ...grpc.CallOption) (*CheckpointReply, error) {
+	out := new(CheckpointReply)
+	err := c.cc.Invoke(ctx, CelestialService_Checkpoint_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *celestialServiceClient) History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (CelestialService_HistoryClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CelestialService_ServiceDesc.Streams[2], CelestialService_History_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &celestialServiceHistoryClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CelestialService_HistoryClient interface {
+	Recv() (*Data, error)
+	grpc.ClientStream
+}
+
+type celestialServiceHistoryClient struct {
+	grpc.ClientStream
+}
+
+func (x *celestialServiceHistoryClient) Recv() (*Data, error) {
+	m := new(Data)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // CelestialServiceServer is the server API for CelestialService service.
 // All implementations must embed UnimplementedCelestialServiceServer
 // for forward compatibility
 type CelestialServiceServer interface {
 	CelestialUpdate(CelestialService_CelestialUpdateServer) error
 	CelestialBodiesPositions(*CelestialBodiesPositionRequest, CelestialService_CelestialBodiesPositionsServer) error
+	Checkpoint(context.Context, *CheckpointRequest) (*CheckpointReply, error)
+	History(*HistoryRequest, CelestialService_HistoryServer) error
 	mustEmbedUnimplementedCelestialServiceServer()
 }
 
@@ -121,6 +168,12 @@ func (UnimplementedCelestialServiceServer) CelestialUpdate(CelestialService_Cele
 func (UnimplementedCelestialServiceServer) CelestialBodiesPositions(*CelestialBodiesPositionRequest, CelestialService_CelestialBodiesPositionsServer) error {
 	return status.Errorf(codes.Unimplemented, "method CelestialBodiesPositions not implemented")
 }
+func (UnimplementedCelestialServiceServer) Checkpoint(context.Context, *CheckpointRequest) (*CheckpointReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Checkpoint not implemented")
+}
+func (UnimplementedCelestialServiceServer) History(*HistoryRequest, CelestialService_HistoryServer) error {
+	return status.Errorf(codes.Unimplemented, "method History not implemented")
+}
 func (UnimplementedCelestialServiceServer) mustEmbedUnimplementedCelestialServiceServer() {}
 
 // UnsafeCelestialServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -181,13 +234,57 @@ func (x *celestialServiceCelestialBodiesPositionsServer) Send(m *Data) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _CelestialService_Checkpoint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckpointRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CelestialServiceServer).Checkpoint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CelestialService_Checkpoint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CelestialServiceServer).Checkpoint(ctx, req.(*CheckpointRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CelestialService_History_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(HistoryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CelestialServiceServer).History(m, &celestialServiceHistoryServer{stream})
+}
+
+type CelestialService_HistoryServer interface {
+	Send(*Data) error
+	grpc.ServerStream
+}
+
+type celestialServiceHistoryServer struct {
+	grpc.ServerStream
+}
+
+func (x *celestialServiceHistoryServer) Send(m *Data) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // CelestialService_ServiceDesc is the grpc.ServiceDesc for CelestialService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var CelestialService_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "taiyoukei.CelestialService",
 	HandlerType: (*CelestialServiceServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Checkpoint",
+			Handler:    _CelestialService_Checkpoint_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "CelestialUpdate",
@@ -200,6 +297,249 @@ var CelestialService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _CelestialService_CelestialBodiesPositions_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "History",
+			Handler:       _CelestialService_History_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "celestial.proto",
+}
+
+const (
+	ControlService_Pause_FullMethodName           = "/taiyoukei.ControlService/Pause"
+	ControlService_Resume_FullMethodName          = "/taiyoukei.ControlService/Resume"
+	ControlService_StepN_FullMethodName           = "/taiyoukei.ControlService/StepN"
+	ControlService_Shutdown_FullMethodName        = "/taiyoukei.ControlService/Shutdown"
+	ControlService_ScheduleImpulse_FullMethodName = "/taiyoukei.ControlService/ScheduleImpulse"
+)
+
+// ControlServiceClient is the client API for ControlService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ControlServiceClient interface {
+	Pause(ctx context.Context, in *ControlRequest, opts ...grpc.CallOption) (*ControlReply, error)
+	Resume(ctx context.Context, in *ControlRequest, opts ...grpc.CallOption) (*ControlReply, error)
+	StepN(ctx context.Context, in *StepRequest, opts ...grpc.CallOption) (*ControlReply, error)
+	Shutdown(ctx context.Context, in *ControlRequest, opts ...grpc.CallOption) (*ControlReply, error)
+	ScheduleImpulse(ctx context.Context, in *Impulse, opts ...grpc.CallOption) (*ControlReply, error)
+}
+
+type controlServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewControlServiceClient(cc grpc.ClientConnInterface) ControlServiceClient {
+	return &controlServiceClient{cc}
+}
+
+func (c *controlServiceClient) Pause(ctx context.Context, in *ControlRequest, opts ...grpc.CallOption) (*ControlReply, error) {
+	out := new(ControlReply)
+	err := c.cc.Invoke(ctx, ControlService_Pause_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) Resume(ctx context.Context, in *ControlRequest, opts ...grpc.CallOption) (*ControlReply, error) {
+	out := new(ControlReply)
+	err := c.cc.Invoke(ctx, ControlService_Resume_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) StepN(ctx context.Context, in *StepRequest, opts ...grpc.CallOption) (*ControlReply, error) {
+	out := new(ControlReply)
+	err := c.cc.Invoke(ctx, ControlService_StepN_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) Shutdown(ctx context.Context, in *ControlRequest, opts ...grpc.CallOption) (*ControlReply, error) {
+	out := new(ControlReply)
+	err := c.cc.Invoke(ctx, ControlService_Shutdown_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) ScheduleImpulse(ctx context.Context, in *Impulse, opts ...grpc.CallOption) (*ControlReply, error) {
+	out := new(ControlReply)
+	err := c.cc.Invoke(ctx, ControlService_ScheduleImpulse_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ControlServiceServer is the server API for ControlService service.
+// All implementations must embed UnimplementedControlServiceServer
+// for forward compatibility
+type ControlServiceServer interface {
+	Pause(context.Context, *ControlRequest) (*ControlReply, error)
+	Resume(context.Context, *ControlRequest) (*ControlReply, error)
+	StepN(context.Context, *StepRequest) (*ControlReply, error)
+	Shutdown(context.Context, *ControlRequest) (*ControlReply, error)
+	ScheduleImpulse(context.Context, *Impulse) (*ControlReply, error)
+	mustEmbedUnimplementedControlServiceServer()
+}
+
+// UnimplementedControlServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedControlServiceServer struct {
+}
+
+func (UnimplementedControlServiceServer) Pause(context.Context, *ControlRequest) (*ControlReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Pause not implemented")
+}
+func (UnimplementedControlServiceServer) Resume(context.Context, *ControlRequest) (*ControlReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Resume not implemented")
+}
+func (UnimplementedControlServiceServer) StepN(context.Context, *StepRequest) (*ControlReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StepN not implemented")
+}
+func (UnimplementedControlServiceServer) Shutdown(context.Context, *ControlRequest) (*ControlReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Shutdown not implemented")
+}
+func (UnimplementedControlServiceServer) ScheduleImpulse(context.Context, *Impulse) (*ControlReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ScheduleImpulse not implemented")
+}
+func (UnimplementedControlServiceServer) mustEmbedUnimplementedControlServiceServer() {}
+
+// UnsafeControlServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ControlServiceServer will
+// result in compilation errors.
+type UnsafeControlServiceServer interface {
+	mustEmbedUnimplementedControlServiceServer()
+}
+
+func RegisterControlServiceServer(s grpc.ServiceRegistrar, srv ControlServiceServer) {
+	s.RegisterService(&ControlService_ServiceDesc, srv)
+}
+
+func _ControlService_Pause_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ControlRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).Pause(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ControlService_Pause_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).Pause(ctx, req.(*ControlRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_Resume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ControlRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).Resume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ControlService_Resume_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).Resume(ctx, req.(*ControlRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_StepN_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StepRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).StepN(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ControlService_StepN_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).StepN(ctx, req.(*StepRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_Shutdown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ControlRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).Shutdown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ControlService_Shutdown_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).Shutdown(ctx, req.(*ControlRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_ScheduleImpulse_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Impulse)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).ScheduleImpulse(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ControlService_ScheduleImpulse_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).ScheduleImpulse(ctx, req.(*Impulse))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ControlService_ServiceDesc is the grpc.ServiceDesc for ControlService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ControlService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "taiyoukei.ControlService",
+	HandlerType: (*ControlServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Pause",
+			Handler:    _ControlService_Pause_Handler,
+		},
+		{
+			MethodName: "Resume",
+			Handler:    _ControlService_Resume_Handler,
+		},
+		{
+			MethodName: "StepN",
+			Handler:    _ControlService_StepN_Handler,
+		},
+		{
+			MethodName: "Shutdown",
+			Handler:    _ControlService_Shutdown_Handler,
+		},
+		{
+			MethodName: "ScheduleImpulse",
+			Handler:    _ControlService_ScheduleImpulse_Handler,
+		},
 	},
+	Streams:  []grpc.StreamDesc{},
 	Metadata: "celestial.proto",
 }
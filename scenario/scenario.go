@@ -0,0 +1,289 @@
+// Package scenario reads the JSON files describing a whole system: the server settings
+// and the initial conditions of every body.
+package scenario
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"taiyoukei/physics"
+	"taiyoukei/units"
+)
+
+const DEFAULT_PORT = 50051
+const DEFAULT_SOLVER = "rk4"
+const DEFAULT_DT = 0.00001
+const DEFAULT_UNITS = "canonical"
+const DEFAULT_TOLERANCE = 1e-9
+
+type Server struct {
+	Port int     `json:"port"`
+	N    int     `json:"n"`  // Bodies required to start, defaults to the bodies joining at round 0
+	Dt   float64 `json:"dt"` // Step used when no client proposes one
+}
+
+type Body struct {
+	Name      string  `json:"name"`
+	Mass      float64 `json:"mass"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	Z         float64 `json:"z"`
+	Vx        float64 `json:"vx"`
+	Vy        float64 `json:"vy"`
+	Vz        float64 `json:"vz"`
+	Radius    float64 `json:"radius"`
+	Solver    string  `json:"solver"`    // Defaults to the scenario solver
+	Dt        float64 `json:"dt"`        // Defaults to the scenario dt
+	Tolerance float64 `json:"tolerance"` // Only used by adaptive solvers
+	Join      uint64  `json:"join"`
+	Leave     uint64  `json:"leave"`
+	Server    bool    `json:"server"` // Integrated by the server instead of a client
+	// Integrated in the field of the other bodies but exerting no force
+	TestParticle bool `json:"test_particle"`
+	// File of test particles integrated by the client of the body, see client/run.go
+	Particles string `json:"particles"`
+	// File of further bodies integrated by the client of the body, such as the moons of a
+	// planet, see client/run.go
+	Group string `json:"group"`
+	// File of the thrust schedule of a spacecraft, see LoadThrust
+	Thrust string `json:"thrust"`
+	// Orbital elements around a parent body, replacing the Cartesian state when Parent is set
+	Parent      string  `json:"parent"`
+	A           float64 `json:"a"`
+	E           float64 `json:"e"`
+	Omega       float64 `json:"omega"`
+	MeanAnomaly float64 `json:"mean_anomaly"`
+	Inclination float64 `json:"inclination"`
+	Node        float64 `json:"node"`
+	// Force models, Newtonian gravity only when empty
+	Force         string  `json:"force"`
+	Softening     float64 `json:"softening"`
+	Theta         float64 `json:"theta"`   // Barnes-Hut opening angle, 0 for the exact sum
+	Primary       string  `json:"primary"` // Defaults to Parent
+	J2            float64 `json:"j2"`
+	PrimaryRadius float64 `json:"primary_radius"`
+}
+
+type Scenario struct {
+	Server Server  `json:"server"`
+	Solver string  `json:"solver"` // Default solver of the bodies
+	Dt     float64 `json:"dt"`     // Default step proposed by the bodies
+	Units  string  `json:"units"`  // Unit system of every quantity in the file
+	Bodies []Body  `json:"bodies"`
+	Path   string  `json:"-"` // File the scenario was loaded from
+}
+
+// Load reads a scenario file and fills in the defaults
+func Load(path string) (*Scenario, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := Scenario{Path: path}
+	if err := json.Unmarshal(content, &s); err != nil {
+		return nil, fmt.Errorf("could not parse %v: %w", path, err)
+	}
+	if err := s.setDefaults(); err != nil {
+		return nil, fmt.Errorf("invalid scenario %v: %w", path, err)
+	}
+	return &s, nil
+}
+
+func (s *Scenario) setDefaults() error {
+	if len(s.Bodies) == 0 {
+		return errors.New("no bodies")
+	}
+	if s.Server.Port == 0 {
+		s.Server.Port = DEFAULT_PORT
+	}
+	if s.Solver == "" {
+		s.Solver = DEFAULT_SOLVER
+	}
+	if s.Dt == 0 {
+		s.Dt = DEFAULT_DT
+	}
+	if s.Server.Dt == 0 {
+		s.Server.Dt = s.Dt
+	}
+	if s.Units == "" {
+		s.Units = DEFAULT_UNITS
+	}
+	if _, err := units.Parse(s.Units); err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	initial := 0
+	for i := range s.Bodies {
+		body := &s.Bodies[i]
+		if body.Name == "" {
+			return fmt.Errorf("body %v has no name", i)
+		}
+		if names[body.Name] {
+			return fmt.Errorf("body %v is defined twice", body.Name)
+		}
+		names[body.Name] = true
+		if body.Solver == "" {
+			body.Solver = s.Solver
+		}
+		if body.Dt == 0 {
+			body.Dt = s.Dt
+		}
+		if body.Tolerance == 0 {
+			body.Tolerance = DEFAULT_TOLERANCE
+		}
+		if body.Server && body.Parent != "" {
+			return fmt.Errorf("body %v is integrated by the server and needs a Cartesian state", body.Name)
+		}
+		if body.Server && (body.Particles != "" || body.Group != "") {
+			return fmt.Errorf("body %v is integrated by the server and cannot carry other bodies", body.Name)
+		}
+		if body.Server && body.Thrust != "" {
+			return fmt.Errorf("body %v is integrated by the server and cannot thrust", body.Name)
+		}
+		if body.Join == 0 {
+			initial++
+			// The group and test particles of the body join along with it
+			for _, path := range []string{body.Group, body.Particles} {
+				if path == "" {
+					continue
+				}
+				group, err := LoadGroup(path)
+				if err != nil {
+					return err
+				}
+				initial += len(group)
+			}
+		}
+	}
+	if s.Server.N == 0 {
+		s.Server.N = initial
+	}
+	return nil
+}
+
+// LoadGroup reads a file of bodies sent along with another one: a JSON list of bodies of
+// which only the name, mass, position, speed, radius and test particle flag are used
+func LoadGroup(path string) ([]Body, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	group := make([]Body, 0)
+	if err := json.Unmarshal(content, &group); err != nil {
+		return nil, fmt.Errorf("could not parse %v: %w", path, err)
+	}
+	return group, nil
+}
+
+// LoadThrust reads a thrust schedule: a JSON list of segments, each with its start and
+// stop times, direction (prograde, radial or fixed along x, y, z), acceleration and
+// mass_flow
+func LoadThrust(path string) ([]physics.ThrustSegment, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	segments := make([]physics.ThrustSegment, 0)
+	if err := json.Unmarshal(content, &segments); err != nil {
+		return nil, fmt.Errorf("could not parse %v: %w", path, err)
+	}
+	for i := range segments {
+		if err := segments[i].Validate(); err != nil {
+			return nil, fmt.Errorf("segment %v of %v: %w", i, path, err)
+		}
+	}
+	return segments, nil
+}
+
+// ServerArgs are the command line arguments of server/run.go
+func (s *Scenario) ServerArgs() []string {
+	args := []string{
+		"-server", strconv.Itoa(s.Server.Port),
+		"-n", strconv.Itoa(s.Server.N),
+		"-dt", formatFloat(s.Server.Dt),
+		"-units", s.Units,
+	}
+	if len(s.ServerBodies()) > 0 {
+		args = append(args, "-scenario", s.Path)
+	}
+	return args
+}
+
+// ServerBodies are the bodies integrated by the server
+func (s *Scenario) ServerBodies() []Body {
+	bodies := make([]Body, 0)
+	for _, body := range s.Bodies {
+		if body.Server {
+			bodies = append(bodies, body)
+		}
+	}
+	return bodies
+}
+
+// ClientArgs are the command line arguments of client/run.go for the given body
+func (s *Scenario) ClientArgs(body Body) []string {
+	args := []string{
+		"-server", ":" + strconv.Itoa(s.Server.Port),
+		"-name", body.Name,
+		"-mass", formatFloat(body.Mass),
+		"-x", formatFloat(body.X),
+		"-y", formatFloat(body.Y),
+		"-z", formatFloat(body.Z),
+		"-vx", formatFloat(body.Vx),
+		"-vy", formatFloat(body.Vy),
+		"-vz", formatFloat(body.Vz),
+		"-radius", formatFloat(body.Radius),
+		"-solver", body.Solver,
+		"-dt", formatFloat(body.Dt),
+		"-join", strconv.FormatUint(body.Join, 10),
+		"-leave", strconv.FormatUint(body.Leave, 10),
+		"-units", s.Units,
+	}
+	if body.TestParticle {
+		args = append(args, "-test-particle")
+	}
+	if body.Particles != "" {
+		args = append(args, "-particles", body.Particles)
+	}
+	if body.Group != "" {
+		args = append(args, "-group", body.Group)
+	}
+	if body.Thrust != "" {
+		args = append(args, "-thrust", body.Thrust)
+	}
+	args = append(args, "-tolerance", formatFloat(body.Tolerance))
+	if body.Parent != "" {
+		args = append(args,
+			"-parent", body.Parent,
+			"-a", formatFloat(body.A),
+			"-e", formatFloat(body.E),
+			"-omega", formatFloat(body.Omega),
+			"-M", formatFloat(body.MeanAnomaly),
+			"-inc", formatFloat(body.Inclination),
+			"-node", formatFloat(body.Node),
+		)
+	}
+	if body.Force != "" {
+		args = append(args, "-force", body.Force)
+	}
+	if body.Softening != 0 {
+		args = append(args, "-softening", formatFloat(body.Softening))
+	}
+	if body.Theta != 0 {
+		args = append(args, "-theta", formatFloat(body.Theta))
+	}
+	if body.Primary != "" {
+		args = append(args, "-primary", body.Primary)
+	}
+	if body.J2 != 0 {
+		args = append(args, "-j2", formatFloat(body.J2), "-primary-radius", formatFloat(body.PrimaryRadius))
+	}
+	return args
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,103 @@
+package scenario
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func write(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDefaultsAreFilledIn(t *testing.T) {
+	path := write(t, t.TempDir(), "minimal.json", `{"bodies": [{"name": "A", "mass": 1}]}`)
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Solver != DEFAULT_SOLVER || s.Dt != DEFAULT_DT || s.Units != DEFAULT_UNITS {
+		t.Fatalf("expected the default solver, step and units, got %v, %v and %v", s.Solver, s.Dt, s.Units)
+	}
+	if s.Server.Port != DEFAULT_PORT || s.Server.N != 1 || s.Server.Dt != DEFAULT_DT {
+		t.Fatalf("unexpected server settings %+v", s.Server)
+	}
+	a := s.Bodies[0]
+	if a.Solver != DEFAULT_SOLVER || a.Dt != DEFAULT_DT || a.Tolerance != DEFAULT_TOLERANCE {
+		t.Fatalf("expected A to get the defaults, got %+v", a)
+	}
+}
+
+func TestScenarioIsResolved(t *testing.T) {
+	dir := t.TempDir()
+	moons := write(t, dir, "moons.json", `[{"name": "M1", "mass": 0.01, "x": 1.1}, {"name": "M2", "mass": 0.01, "x": 0.9}]`)
+	path := write(t, dir, "system.json", `{
+		"server": {"port": 50060},
+		"solver": "leapfrog",
+		"dt": 0.01,
+		"units": "astronomical",
+		"bodies": [
+			{"name": "S", "mass": 1, "server": true},
+			{"name": "E", "mass": 0.001, "x": 1, "vy": 1, "group": "`+moons+`"},
+			{"name": "D", "solver": "dopri5", "tolerance": 1e-6, "parent": "S", "a": 2, "e": 0.1},
+			{"name": "P", "x": 3, "join": 5, "test_particle": true}
+		]
+	}`)
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// S, E with its two moons and D start the simulation, P joins later
+	if s.Server.N != 5 || s.Server.Dt != 0.01 {
+		t.Fatalf("unexpected server settings %+v", s.Server)
+	}
+	if server := s.ServerBodies(); len(server) != 1 || server[0].Name != "S" {
+		t.Fatalf("expected S only to be integrated by the server, got %v", server)
+	}
+	expected := []string{"-server", "50060", "-n", "5", "-dt", "0.01", "-units", "astronomical", "-scenario", path}
+	if args := s.ServerArgs(); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("unexpected server arguments %v", args)
+	}
+
+	expected = []string{
+		"-server", ":50060", "-name", "E", "-mass", "0.001",
+		"-x", "1", "-y", "0", "-z", "0", "-vx", "0", "-vy", "1", "-vz", "0",
+		"-radius", "0", "-solver", "leapfrog", "-dt", "0.01", "-join", "0", "-leave", "0",
+		"-units", "astronomical", "-group", moons, "-tolerance", "1e-09",
+	}
+	if args := s.ClientArgs(s.Bodies[1]); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("unexpected arguments for E %v", args)
+	}
+	expected = []string{
+		"-server", ":50060", "-name", "D", "-mass", "0",
+		"-x", "0", "-y", "0", "-z", "0", "-vx", "0", "-vy", "0", "-vz", "0",
+		"-radius", "0", "-solver", "dopri5", "-dt", "0.01", "-join", "0", "-leave", "0",
+		"-units", "astronomical", "-tolerance", "1e-06",
+		"-parent", "S", "-a", "2", "-e", "0.1", "-omega", "0", "-M", "0", "-inc", "0", "-node", "0",
+	}
+	if args := s.ClientArgs(s.Bodies[2]); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("unexpected arguments for D %v", args)
+	}
+	expected = []string{
+		"-server", ":50060", "-name", "P", "-mass", "0",
+		"-x", "3", "-y", "0", "-z", "0", "-vx", "0", "-vy", "0", "-vz", "0",
+		"-radius", "0", "-solver", "leapfrog", "-dt", "0.01", "-join", "5", "-leave", "0",
+		"-units", "astronomical", "-test-particle", "-tolerance", "1e-09",
+	}
+	if args := s.ClientArgs(s.Bodies[3]); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("unexpected arguments for P %v", args)
+	}
+}
+
+func TestServerBodyNeedsACartesianState(t *testing.T) {
+	path := write(t, t.TempDir(), "invalid.json", `{"bodies": [{"name": "S", "mass": 1}, {"name": "E", "parent": "S", "a": 1, "server": true}]}`)
+	if _, err := Load(path); err == nil {
+		t.Fatalf("expected a server body with orbital elements to be rejected")
+	}
+}
@@ -1,20 +1,30 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"log"
 	"math"
 	"net"
+	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
-	"time"
 
+	"taiyoukei/physics"
 	pb "taiyoukei/proto"
+	"taiyoukei/scenario"
+	"taiyoukei/units"
 	// protoc --go_out=. --go-grpc_out=. --proto_path=proto/ proto/celestial.proto
 
 	"github.com/google/uuid"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 )
 
 const EMPTY_STR = ""
@@ -23,13 +33,29 @@ const ZERO = 0
 const ONE = 1
 
 type argParser struct {
-	port int
-	n    int
+	port            int
+	n               int
+	dt              float64
+	checkpoint      string
+	checkpointEvery uint64
+	restore         string
+	maxDrift        float64
+	units           string
+	scenario        string
+	history         int
 }
 
 func (parser *argParser) parse() {
 	flag.IntVar(&parser.port, "server", 50051, "The port to use (in integer format)")
-	flag.IntVar(&parser.n, "n", 3, "Number of Orbiting bodies: routine won't start unless that number is equal to the number of connections")
+	flag.IntVar(&parser.n, "n", 3, "Number of Orbiting bodies, test particles included: routine won't start until that many bodies reported, later bodies join at round boundaries")
+	flag.Float64Var(&parser.dt, "dt", 0.00001, "Step size used for a round when no client proposes one")
+	flag.StringVar(&parser.checkpoint, "checkpoint", "checkpoint.json", "File the simulation state is saved to")
+	flag.Uint64Var(&parser.checkpointEvery, "checkpoint-every", 0, "Save the simulation state every that many rounds (0 to only save on request)")
+	flag.StringVar(&parser.restore, "restore", EMPTY_STR, "Checkpoint file to restore the simulation from, clients reattach by name")
+	flag.StringVar(&parser.units, "units", "canonical", "Unit system of the simulation: canonical (G=1), si or astronomical (AU, solar mass, day)")
+	flag.StringVar(&parser.scenario, "scenario", EMPTY_STR, "Scenario file whose bodies marked \"server\" are integrated by the server itself")
+	flag.IntVar(&parser.history, "history", HISTORY_SIZE, "Number of past rounds kept for the History RPC (0 to keep none)")
+	flag.Float64Var(&parser.maxDrift, "max-drift", 0, "Abort the simulation when the relative drift of a conserved quantity exceeds it (0 to only log the drift)")
 	flag.Parse()
 }
 
@@ -39,15 +65,77 @@ type lightCelestialBody struct {
 	mass     float64
 	x        float64
 	y        float64
+	z        float64
 	vx       float64
 	vy       float64
+	vz       float64
+	dt       float64
+	radius   float64
+	solver   string
+	// Test particles are integrated in the field of the other bodies but exert no force
+	testParticle bool
+	grouped      bool // Sent along with the body of another stream
+	thrusting    bool // Accelerated by its own thrust over the last round
+}
+
+func newLightCelestialBody(data *pb.CelestialBody) lightCelestialBody {
+	return lightCelestialBody{
+		sequence:     data.Sequence,
+		name:         data.Name,
+		mass:         data.Mass,
+		x:            data.X,
+		y:            data.Y,
+		z:            data.Z,
+		vx:           data.Vx,
+		vy:           data.Vy,
+		vz:           data.Vz,
+		dt:           data.Dt,
+		radius:       data.Radius,
+		solver:       data.Solver,
+		testParticle: data.TestParticle,
+		thrusting:    data.Thrusting,
+	}
+}
+
+func (body *lightCelestialBody) validate() error {
+	if math.IsNaN(body.x) {
+		return errors.New("x is nan")
+	} else if math.IsNaN(body.y) {
+		return errors.New("y is nan")
+	} else if math.IsNaN(body.z) {
+		return errors.New("z is nan")
+	} else if math.IsNaN(body.vx) {
+		return errors.New("vx is nan")
+	} else if math.IsNaN(body.vy) {
+		return errors.New("vy is nan")
+	} else if math.IsNaN(body.vz) {
+		return errors.New("vz is nan")
+	}
+	return nil
 }
 
 type connection struct {
-	id     uuid.UUID
-	name   string
-	data   lightCelestialBody
-	stream pb.CelestialService_CelestialUpdateServer
+	id         uuid.UUID
+	name       string
+	data       lightCelestialBody
+	group      []lightCelestialBody // Bodies sent along with the body
+	stream     pb.CelestialService_CelestialUpdateServer
+	done       chan struct{} // Closed when the server ends the connection
+	err        error
+	terminated bool
+	joinRound  uint64
+	admitted   bool       // Whether the body takes part in the rounds
+	owned      *ownedBody // Set for bodies integrated by the server, which have no stream
+}
+
+func newConnection(id uuid.UUID, stream pb.CelestialService_CelestialUpdateServer) *connection {
+	return &connection{
+		id:     id,
+		name:   EMPTY_STR,
+		data:   lightCelestialBody{},
+		stream: stream,
+		done:   make(chan struct{}),
+	}
 }
 
 func (c *connection) setName(name string) {
@@ -58,20 +146,25 @@ func (c *connection) setData(data *pb.CelestialBody) error {
 	if c.data != (lightCelestialBody{}) {
 		return errors.New("data is already set")
 	}
-	c.data = lightCelestialBody{
-		sequence: data.Sequence,
-		name:     data.Name,
-		mass:     data.Mass,
-		x:        data.X,
-		y:        data.Y,
-		vx:       data.Vx,
-		vy:       data.Vy,
+	group := make([]lightCelestialBody, 0, len(data.Group))
+	for _, member := range data.Group {
+		body := newLightCelestialBody(member)
+		body.grouped = true
+		group = append(group, body)
 	}
+	c.data = newLightCelestialBody(data)
+	c.group = group
 	return nil
 }
 
 func (c *connection) resetData() {
 	c.data = lightCelestialBody{}
+	c.group = nil
+}
+
+// The body of the connection followed by its group
+func (c *connection) bodies() []lightCelestialBody {
+	return append([]lightCelestialBody{c.data}, c.group...)
 }
 
 func (c *connection) getName() string {
@@ -86,195 +179,1345 @@ func (c *connection) isReadyForBroadcast() bool {
 	return c.data != lightCelestialBody{}
 }
 
-type server struct {
-	pb.UnimplementedCelestialServiceServer
+func (c *connection) terminate(err error) {
+	if c.terminated {
+		return
+	}
+	c.terminated = true
+	c.err = err
+	close(c.done)
+}
+
+// -------------------------------------------------------------------------------------
+// Round coordinator
+//
+// Every connection reports the state of its body once per round, along with the bodies of
+// its group if any (e.g. a planet and its moons integrated by the same client), in a
+// single message. The broadcast goroutine sleeps on a condition variable that is
+// signalled whenever a connection joins, leaves or reports, and only wakes up to check
+// the barrier at those moments.
+//
+// The simulation starts once n bodies reported, counting the bodies of the groups. From
+// then on the barrier only waits for the admitted connections: a body leaving is simply
+// dropped from the next round, and a body connecting later is admitted at the first
+// round boundary after its requested round. A group joins and leaves with its body.
+//
+// While paused, the barrier holds the broadcast of the collected round back, unless steps
+// are left to broadcast.
+
+type roundCoordinator struct {
 	mutex       sync.Mutex
+	cond        *sync.Cond
 	connections map[uuid.UUID]*connection
-	archive     pb.Data
 	n           int
+	round       uint64
+	started     bool
+	closed      bool
+	paused      bool
+	steps       uint64                        // Rounds broadcast while paused
+	restored    map[string]lightCelestialBody // Checkpointed bodies waiting for their client
+}
+
+func newRoundCoordinator(n int) *roundCoordinator {
+	rc := &roundCoordinator{
+		connections: make(map[uuid.UUID]*connection),
+		n:           n,
+	}
+	rc.cond = sync.NewCond(&rc.mutex)
+	return rc
 }
 
-func (s *server) isReadyForBroadcast() bool {
-	for _, c := range s.connections {
+func (rc *roundCoordinator) join(c *connection) {
+	rc.mutex.Lock()
+	if rc.closed {
+		c.terminate(errors.New("the simulation is over"))
+	}
+	rc.connections[c.id] = c
+	rc.mutex.Unlock()
+	rc.cond.Broadcast()
+}
+
+func (rc *roundCoordinator) leave(id uuid.UUID) {
+	rc.mutex.Lock()
+	delete(rc.connections, id)
+	rc.mutex.Unlock()
+	rc.cond.Broadcast()
+}
+
+func (rc *roundCoordinator) submit(id uuid.UUID, data *pb.CelestialBody) error {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	c, ok := rc.connections[id]
+	if !ok {
+		return errors.New("connection is not part of the round")
+	}
+	c.setName(data.Name)
+	if err := c.setData(data); err != nil {
+		return err
+	}
+	if !c.admitted {
+		c.joinRound = data.JoinRound
+		if body, ok := rc.restored[c.name]; ok {
+			log.Printf("%v reattaches to its checkpointed state", c.name)
+			c.data = body
+			c.joinRound = ZERO
+			delete(rc.restored, c.name)
+		}
+		for i := range c.group {
+			if body, ok := rc.restored[c.group[i].name]; ok {
+				body.grouped = true
+				c.group[i] = body
+				delete(rc.restored, body.name)
+			}
+		}
+	}
+	log.Printf("Received data from %v: %v", c.name, c.data)
+	rc.cond.Broadcast()
+	return nil
+}
+
+func (rc *roundCoordinator) getName(id uuid.UUID) string {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	c, ok := rc.connections[id]
+	if !ok {
+		return UNDEFINED
+	}
+	return c.getName()
+}
+
+// Admits the reported bodies whose join round is reached. Before the simulation starts,
+// only bodies joining at round 0 are admitted. Must be called with the mutex held.
+func (rc *roundCoordinator) admit() {
+	for _, c := range rc.connections {
+		if c.admitted || !c.isReadyForBroadcast() {
+			continue
+		}
+		if c.joinRound > rc.round || (!rc.started && c.joinRound > ZERO) {
+			continue
+		}
+		c.admitted = true
+		log.Printf("%v joins the simulation at round %v", c.getName(), rc.round)
+	}
+}
+
+// Must be called with the mutex held
+func (rc *roundCoordinator) isReadyForBroadcast() bool {
+	members := 0
+	for _, c := range rc.connections {
+		if !c.admitted {
+			continue
+		}
 		if !c.isReadyForBroadcast() {
 			return false
 		}
+		members += ONE + len(c.group)
+	}
+	if !rc.started {
+		return members >= rc.n
+	}
+	return members > ZERO
+}
+
+// Blocks until all admitted bodies reported, then hands over their data and resets it so
+// that data acquisition from clients can immediately resume. Returns the connections of
+// the round, their bodies along with the connection each one belongs to, the index of the
+// round, and false once closed.
+func (rc *roundCoordinator) awaitRound() ([]*connection, []lightCelestialBody, []*connection, uint64, bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	for {
+		if rc.closed {
+			return nil, nil, nil, ZERO, false
+		}
+		rc.admit()
+		if rc.isReadyForBroadcast() && (!rc.paused || rc.steps > ZERO) {
+			break
+		}
+		rc.cond.Wait()
+	}
+	if rc.paused {
+		rc.steps--
+	}
+	members := make([]*connection, 0, len(rc.connections))
+	bodies := make([]lightCelestialBody, 0, len(rc.connections))
+	owners := make([]*connection, 0, len(rc.connections))
+	for _, c := range rc.connections {
+		if !c.admitted {
+			continue
+		}
+		members = append(members, c)
+		for _, body := range c.bodies() {
+			bodies = append(bodies, body)
+			owners = append(owners, c)
+		}
+		c.resetData()
+	}
+	round := rc.round
+	rc.started = true
+	rc.round++
+	return members, bodies, owners, round, true
+}
+
+// Removes the body of the given connection from the simulation and ends its
+// CelestialUpdate call with err
+func (rc *roundCoordinator) terminate(c *connection, err error) {
+	rc.mutex.Lock()
+	c.terminate(err)
+	delete(rc.connections, c.id)
+	rc.mutex.Unlock()
+	rc.cond.Broadcast()
+}
+
+// Resumes the rounds from a checkpoint: the simulation starts again once the client of
+// every checkpointed body reattached
+func (rc *roundCoordinator) restore(snapshot *pb.Data) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.round = snapshot.Round
+	rc.n = len(snapshot.Content)
+	rc.restored = make(map[string]lightCelestialBody)
+	for name, datum := range snapshot.Content {
+		rc.restored[name] = newLightCelestialBody(datum)
+	}
+}
+
+func (rc *roundCoordinator) close() {
+	rc.mutex.Lock()
+	rc.closed = true
+	rc.mutex.Unlock()
+	rc.cond.Broadcast()
+}
+
+// Connections still part of the simulation, admitted or not
+func (rc *roundCoordinator) remaining() []*connection {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	connections := make([]*connection, 0, len(rc.connections))
+	for _, c := range rc.connections {
+		connections = append(connections, c)
+	}
+	return connections
+}
+
+// Pauses the rounds when given true, resumes them otherwise
+func (rc *roundCoordinator) pause(paused bool) *pb.ControlReply {
+	rc.mutex.Lock()
+	rc.paused = paused
+	rc.steps = ZERO
+	reply := rc.status()
+	rc.mutex.Unlock()
+	rc.cond.Broadcast()
+	return reply
+}
+
+// Broadcasts n more rounds, then pauses
+func (rc *roundCoordinator) step(n uint64) *pb.ControlReply {
+	rc.mutex.Lock()
+	if !rc.paused {
+		rc.paused = true
+		rc.steps = ZERO
+	}
+	rc.steps += n
+	reply := rc.status()
+	rc.mutex.Unlock()
+	rc.cond.Broadcast()
+	return reply
+}
+
+func (rc *roundCoordinator) report() *pb.ControlReply {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	return rc.status()
+}
+
+// Must be called with the mutex held
+func (rc *roundCoordinator) status() *pb.ControlReply {
+	return &pb.ControlReply{Round: rc.round, Paused: rc.paused, Steps: rc.steps}
+}
+
+// -------------------------------------------------------------------------------------
+
+// -------------------------------------------------------------------------------------
+// Collisions
+//
+// Bodies with a radius collide when their spheres overlap at a round boundary. Colliding
+// bodies merge inelastically into the heavier one, which keeps its name. A test particle
+// is absorbed by the body it hits without changing it, and test particles do not collide
+// with one another.
+
+func (body *lightCelestialBody) overlaps(other *lightCelestialBody) bool {
+	reach := body.radius + other.radius
+	if reach <= ZERO {
+		return false
+	}
+	dx := body.x - other.x
+	dy := body.y - other.y
+	dz := body.z - other.z
+	return dx*dx+dy*dy+dz*dz < reach*reach
+}
+
+// Conserves mass, momentum and volume; the merged body sits at the center of mass
+func merge(survivor lightCelestialBody, absorbed lightCelestialBody) lightCelestialBody {
+	merged := survivor
+	merged.mass = survivor.mass + absorbed.mass
+	merged.radius = math.Cbrt(math.Pow(survivor.radius, 3) + math.Pow(absorbed.radius, 3))
+	if merged.mass == ZERO {
+		return merged
+	}
+	ws := survivor.mass / merged.mass
+	wa := absorbed.mass / merged.mass
+	merged.x = ws*survivor.x + wa*absorbed.x
+	merged.y = ws*survivor.y + wa*absorbed.y
+	merged.z = ws*survivor.z + wa*absorbed.z
+	merged.vx = ws*survivor.vx + wa*absorbed.vx
+	merged.vy = ws*survivor.vy + wa*absorbed.vy
+	merged.vz = ws*survivor.vz + wa*absorbed.vz
+	return merged
+}
+
+// Merges overlapping bodies in place until no overlap is left. Returns which bodies were
+// absorbed and the corresponding mergers.
+func resolveCollisions(bodies []lightCelestialBody) ([]bool, []*pb.Merger) {
+	absorbed := make([]bool, len(bodies))
+	mergers := make([]*pb.Merger, 0)
+	for merging := true; merging; {
+		merging = false
+		for i := range bodies {
+			for j := i + 1; j < len(bodies); j++ {
+				if absorbed[i] || absorbed[j] || (bodies[i].testParticle && bodies[j].testParticle) || !bodies[i].overlaps(&bodies[j]) {
+					continue
+				}
+				survivor, loser := i, j
+				if bodies[i].testParticle || (!bodies[j].testParticle && bodies[j].mass > bodies[i].mass) {
+					survivor, loser = j, i
+				}
+				log.Printf("Collision: %v absorbs %v", bodies[survivor].name, bodies[loser].name)
+				if !bodies[loser].testParticle {
+					bodies[survivor] = merge(bodies[survivor], bodies[loser])
+				}
+				absorbed[loser] = true
+				mergers = append(mergers, &pb.Merger{Absorbed: bodies[loser].name, Survivor: bodies[survivor].name})
+				merging = true
+			}
+		}
+	}
+	return absorbed, mergers
+}
+
+// -------------------------------------------------------------------------------------
+
+// -------------------------------------------------------------------------------------
+// Conserved quantities
+//
+// Total energy, linear momentum and angular momentum of an isolated system are constant:
+// their drift from the values of a reference round measures the integration error. The
+// reference is taken again whenever bodies join, leave, merge, change mass or get an
+// impulse or thrust, as the system itself changes. Test particles are left out as they exert no force.
+
+func computeConservedQuantities(content map[string]*pb.CelestialBody, G float64) physics.ConservedQuantities {
+	bodies := make([]physics.Body, 0, len(content))
+	for name, body := range content {
+		if body.TestParticle {
+			continue
+		}
+		bodies = append(bodies, physics.Body{Name: name, Mass: body.Mass, State: vectorOf(body)})
 	}
-	return true
+	return physics.Conserved(bodies, G)
 }
 
-func (s *server) prepareBroadcastData(data *pb.Data) error {
-	for _, c := range s.connections {
-		if math.IsNaN(c.data.x) {
-			return errors.New("x is nan")
-		} else if math.IsNaN(c.data.y) {
-			return errors.New("y is nan")
-		} else if math.IsNaN(c.data.vx) {
-			return errors.New("vx is nan")
-		} else if math.IsNaN(c.data.vy) {
-			return errors.New("vy is nan")
+func relativeDrift(difference float64, scale float64) float64 {
+	if scale == ZERO {
+		return math.Abs(difference)
+	}
+	return math.Abs(difference / scale)
+}
+
+type conservationMonitor struct {
+	G         float64
+	maxDrift  float64 // 0 disables the abort
+	reference *physics.ConservedQuantities
+	members   string // Bodies and masses of the reference round
+	perturbed bool   // Whether impulses were applied over the previous round
+}
+
+// Whether a body exerting a force was accelerated by its thrust
+func thrusting(content map[string]*pb.CelestialBody) bool {
+	for _, body := range content {
+		if body.Thrusting && !body.TestParticle {
+			return true
 		}
-		data.Content[c.data.name] = &pb.CelestialBody{
-			Sequence: c.data.sequence,
-			Name:     c.data.name,
-			Mass:     c.data.mass,
-			X:        c.data.x,
-			Y:        c.data.y,
-			Vx:       c.data.vx,
-			Vy:       c.data.vy,
+	}
+	return false
+}
+
+// Returns an error when a drift exceeds the threshold
+func (m *conservationMonitor) check(data *pb.Data) error {
+	names := make([]string, 0, len(data.Content))
+	for name, body := range data.Content {
+		if body.TestParticle {
+			continue
 		}
+		names = append(names, fmt.Sprintf("%v:%v", name, body.Mass))
+	}
+	sort.Strings(names)
+	members := strings.Join(names, ",")
+	q := computeConservedQuantities(data.Content, m.G)
+	perturbed := m.perturbed
+	m.perturbed = len(data.Impulses) > ZERO
+	if m.reference == nil || m.members != members || perturbed || thrusting(data.Content) {
+		log.Printf("Conserved quantities reference at round %v: energy %v momentum (%v, %v, %v) angular momentum (%v, %v, %v)", data.Round, q.Energy, q.Px, q.Py, q.Pz, q.Lx, q.Ly, q.Lz)
+		m.reference = &q
+		m.members = members
+		return nil
+	}
+	ref := m.reference
+	energyDrift := relativeDrift(q.Energy-ref.Energy, ref.Energy)
+	momentumDrift := relativeDrift(math.Sqrt(math.Pow(q.Px-ref.Px, 2)+math.Pow(q.Py-ref.Py, 2)+math.Pow(q.Pz-ref.Pz, 2)), ref.MomentumScale)
+	angularDrift := relativeDrift(math.Sqrt(math.Pow(q.Lx-ref.Lx, 2)+math.Pow(q.Ly-ref.Ly, 2)+math.Pow(q.Lz-ref.Lz, 2)), ref.AngularMomentumScale)
+	log.Printf("Relative drift at round %v: energy %e momentum %e angular momentum %e", data.Round, energyDrift, momentumDrift, angularDrift)
+	if m.maxDrift <= ZERO {
+		return nil
+	}
+	if energyDrift > m.maxDrift {
+		return fmt.Errorf("energy drift %e exceeds %e", energyDrift, m.maxDrift)
+	} else if momentumDrift > m.maxDrift {
+		return fmt.Errorf("momentum drift %e exceeds %e", momentumDrift, m.maxDrift)
+	} else if angularDrift > m.maxDrift {
+		return fmt.Errorf("angular momentum drift %e exceeds %e", angularDrift, m.maxDrift)
 	}
 	return nil
 }
 
+// -------------------------------------------------------------------------------------
+
+// -------------------------------------------------------------------------------------
+// Subscriptions
+//
+// A CelestialBodiesPositions subscriber may restrict the snapshots it receives to some
+// bodies and rounds, and have the states made relative to a body or to the barycenter.
+
+const BARYCENTER = "barycenter"
+const SUBSCRIPTION_BUFFER = 16
+const MAX_SUBSCRIPTION_BUFFER = 4096
+
+type subscription struct {
+	names      map[string]bool // Every body when empty
+	decimation uint64
+	frame      string // Inertial frame of the simulation when empty
+	lossless   bool
+	snapshots  chan *pb.Data // Views queued for the subscriber
+	done       chan struct{} // Closed when the subscriber is gone
+	dropped    uint64        // Only accessed by the publisher
+}
+
+func newSubscription(req *pb.CelestialBodiesPositionRequest) *subscription {
+	buffer := int(req.Buffer)
+	if buffer == ZERO {
+		buffer = SUBSCRIPTION_BUFFER
+	}
+	// The queue is allocated upfront, a huge request would exhaust the memory of the server
+	if buffer > MAX_SUBSCRIPTION_BUFFER {
+		buffer = MAX_SUBSCRIPTION_BUFFER
+	}
+	sub := subscription{
+		names:      make(map[string]bool),
+		decimation: req.Decimation,
+		frame:      req.Frame,
+		lossless:   req.Lossless,
+		snapshots:  make(chan *pb.Data, buffer),
+		done:       make(chan struct{}),
+	}
+	for _, name := range req.Names {
+		sub.names[name] = true
+	}
+	if sub.decimation == ZERO {
+		sub.decimation = ONE
+	}
+	return &sub
+}
+
+// Queues the view of a snapshot. When the queue is full, a lossless subscriber is waited
+// for while the oldest queued view of the others is dropped
+func (sub *subscription) publish(snapshot *pb.Data) {
+	if !sub.wants(snapshot.Round) {
+		return
+	}
+	view, err := sub.view(snapshot)
+	// The frame body may not have joined yet, or have been absorbed
+	if err != nil {
+		log.Printf("Skipping snapshot: %v", err)
+		return
+	}
+	sub.enqueue(view)
+}
+
+func (sub *subscription) enqueue(view *pb.Data) {
+	if sub.lossless {
+		select {
+		case sub.snapshots <- view:
+		case <-sub.done:
+		}
+		return
+	}
+	for {
+		view.Dropped = sub.dropped
+		select {
+		case sub.snapshots <- view:
+			return
+		default:
+		}
+		select {
+		case <-sub.snapshots:
+			sub.dropped++
+		default:
+		}
+	}
+}
+
+func (sub *subscription) wants(round uint64) bool {
+	return round%sub.decimation == ZERO
+}
+
+// State of the origin of the frame in the snapshot. The barycenter only accounts for the
+// bodies exerting a force, as test particles do not move it
+func (sub *subscription) origin(snapshot *pb.Data) (physics.Vector, error) {
+	if sub.frame != BARYCENTER {
+		body, ok := snapshot.Content[sub.frame]
+		if !ok {
+			return physics.Vector{}, fmt.Errorf("frame %v is not part of round %v", sub.frame, snapshot.Round)
+		}
+		return vectorOf(body), nil
+	}
+	origin := physics.Vector{}
+	mass := 0.
+	for _, body := range snapshot.Content {
+		if body.TestParticle {
+			continue
+		}
+		state := vectorOf(body)
+		origin = origin.Add(state.ScalarMultiply(body.Mass))
+		mass += body.Mass
+	}
+	if mass == ZERO {
+		return physics.Vector{}, fmt.Errorf("round %v has no mass to center on", snapshot.Round)
+	}
+	return origin.ScalarMultiply(ONE / mass), nil
+}
+
+// Copy of the snapshot holding the requested bodies in the requested frame. A snapshot
+// taken before the first round is empty and returned as is
+func (sub *subscription) view(snapshot *pb.Data) (*pb.Data, error) {
+	view := &pb.Data{
+		Success:  snapshot.Success,
+		Content:  make(map[string]*pb.CelestialBody),
+		Dt:       snapshot.Dt,
+		Time:     snapshot.Time,
+		Round:    snapshot.Round,
+		Mergers:  snapshot.Mergers,
+		Units:    snapshot.Units,
+		Impulses: snapshot.Impulses,
+		Removed:  snapshot.Removed,
+	}
+	if !snapshot.Success {
+		return view, nil
+	}
+	origin := physics.Vector{}
+	if sub.frame != EMPTY_STR {
+		var err error
+		if origin, err = sub.origin(snapshot); err != nil {
+			return nil, err
+		}
+		view.Frame = sub.frame
+	}
+	for name, datum := range snapshot.Content {
+		if len(sub.names) > ZERO && !sub.names[name] {
+			continue
+		}
+		body := proto.Clone(datum).(*pb.CelestialBody)
+		body.X -= origin.X
+		body.Y -= origin.Y
+		body.Z -= origin.Z
+		body.Vx -= origin.Vx
+		body.Vy -= origin.Vy
+		body.Vz -= origin.Vz
+		view.Content[name] = body
+	}
+	return view, nil
+}
+
+// -------------------------------------------------------------------------------------
+
+// -------------------------------------------------------------------------------------
+// History
+//
+// The latest rounds are kept in a ring buffer, so that an observer joining late can
+// backfill the trajectories. A snapshot is never modified once archived, the buffer only
+// holds references to them.
+
+const HISTORY_SIZE = 1000
+
+type history struct {
+	rounds []*pb.Data
+	next   int // Oldest round, overwritten by the next one once the buffer is full
+}
+
+func newHistory(size int) *history {
+	if size < ZERO {
+		size = ZERO
+	}
+	return &history{rounds: make([]*pb.Data, ZERO, size)}
+}
+
+func (h *history) record(snapshot *pb.Data) {
+	if cap(h.rounds) == ZERO {
+		return
+	}
+	if len(h.rounds) < cap(h.rounds) {
+		h.rounds = append(h.rounds, snapshot)
+		return
+	}
+	h.rounds[h.next] = snapshot
+	h.next = (h.next + ONE) % len(h.rounds)
+}
+
+// Rounds matching the request in chronological order
+func (h *history) query(req *pb.HistoryRequest) []*pb.Data {
+	rounds := make([]*pb.Data, ZERO)
+	for i := range h.rounds {
+		snapshot := h.rounds[(h.next+i)%len(h.rounds)]
+		if snapshot.Round < req.FromRound || (req.ToRound > ZERO && snapshot.Round > req.ToRound) {
+			continue
+		}
+		if (req.FromTime != nil && snapshot.Time < *req.FromTime) || (req.ToTime != nil && snapshot.Time > *req.ToTime) {
+			continue
+		}
+		rounds = append(rounds, snapshot)
+	}
+	return rounds
+}
+
+// -------------------------------------------------------------------------------------
+
+// -------------------------------------------------------------------------------------
+// Server
+
+type server struct {
+	pb.UnimplementedCelestialServiceServer
+	mutex           sync.Mutex // Guards the archive and the subscriptions
+	coordinator     *roundCoordinator
+	archive         pb.Data
+	subscriptions   map[*subscription]bool
+	over            bool          // Set once shut down, no new subscription is accepted
+	impulses        []*pb.Impulse // Scheduled and not due yet
+	removed         []string      // Groups of the bodies absorbed in the previous round
+	history         *history
+	dt              float64
+	units           units.System
+	time            float64 // Simulation time of the round being collected
+	checkpointPath  string
+	checkpointEvery uint64
+	monitor         conservationMonitor
+	stop            func() // Stops serving the RPCs once the simulation is shut down
+}
+
+func newServer(n int, dt float64, system units.System) *server {
+	return &server{
+		coordinator: newRoundCoordinator(n),
+		archive: pb.Data{
+			Success: false,
+			Content: make(map[string]*pb.CelestialBody),
+			Units:   system.Units,
+		},
+		subscriptions: make(map[*subscription]bool),
+		history:       newHistory(HISTORY_SIZE),
+		dt:            dt,
+		units:         system,
+		monitor:       conservationMonitor{G: system.G},
+	}
+}
+
+// The step of a round is the smallest one proposed by the clients, so that all bodies
+// stay on the same time grid while close encounters get resolved
+func (s *server) negotiateStep(bodies []lightCelestialBody) float64 {
+	dt := math.Inf(ONE)
+	for _, body := range bodies {
+		if body.dt > ZERO && body.dt < dt {
+			dt = body.dt
+		}
+	}
+	if math.IsInf(dt, ONE) {
+		return s.dt
+	}
+	return dt
+}
+
+// Bodies with an invalid state are removed from the simulation instead of stopping it. The
+// connection of such a body ends and its group is removed along with it, as no client
+// integrates the group anymore, unless the body belongs to the group of another one: the
+// client then learns about it from the broadcast. Removed bodies are listed in data.
+func (s *server) removeInvalidBodies(members []*connection, bodies []lightCelestialBody, owners []*connection, data *pb.Data) ([]*connection, []lightCelestialBody, []*connection) {
+	terminated := make(map[*connection]bool)
+	for i, body := range bodies {
+		if err := body.validate(); err != nil {
+			log.Printf("Removing %v from the simulation: %v", body.name, err)
+			if !body.grouped {
+				s.coordinator.terminate(owners[i], err)
+				terminated[owners[i]] = true
+			}
+		}
+	}
+	validBodies := make([]lightCelestialBody, 0, len(bodies))
+	validOwners := make([]*connection, 0, len(owners))
+	for i, body := range bodies {
+		if body.validate() != nil || terminated[owners[i]] {
+			data.Removed = append(data.Removed, body.name)
+			continue
+		}
+		validBodies = append(validBodies, body)
+		validOwners = append(validOwners, owners[i])
+	}
+	validMembers := make([]*connection, 0, len(members))
+	for _, c := range members {
+		if !terminated[c] {
+			validMembers = append(validMembers, c)
+		}
+	}
+	return validMembers, validBodies, validOwners
+}
+
+func (s *server) prepareBroadcastData(bodies []lightCelestialBody, absorbed []bool, data *pb.Data) {
+	for i, body := range bodies {
+		if absorbed[i] {
+			continue
+		}
+		data.Content[body.name] = &pb.CelestialBody{
+			Sequence:     body.sequence,
+			Name:         body.name,
+			Mass:         body.mass,
+			X:            body.x,
+			Y:            body.y,
+			Z:            body.z,
+			Vx:           body.vx,
+			Vy:           body.vy,
+			Vz:           body.vz,
+			Dt:           body.dt,
+			Radius:       body.radius,
+			Solver:       body.solver,
+			TestParticle: body.testParticle,
+			Thrusting:    body.thrusting,
+		}
+	}
+}
+
 func (s *server) archiveBroadcastData(data *pb.Data) {
 	s.mutex.Lock()
 	s.archive.Success = data.Success
+	s.archive.Dt = data.Dt
+	s.archive.Time = data.Time
+	s.archive.Round = data.Round
+	s.archive.Units = data.Units
+	// Bodies that left the simulation are no longer part of the snapshot
+	s.archive.Content = make(map[string]*pb.CelestialBody)
 	for name, datum := range data.Content {
 		s.archive.Content[name] = &pb.CelestialBody{
-			Sequence: datum.Sequence,
-			Name:     datum.Name,
-			Mass:     datum.Mass,
-			X:        datum.X,
-			Y:        datum.Y,
-			Vx:       datum.Vx,
-			Vy:       datum.Vy,
+			Sequence:     datum.Sequence,
+			Name:         datum.Name,
+			Mass:         datum.Mass,
+			X:            datum.X,
+			Y:            datum.Y,
+			Z:            datum.Z,
+			Vx:           datum.Vx,
+			Vy:           datum.Vy,
+			Vz:           datum.Vz,
+			Dt:           datum.Dt,
+			Radius:       datum.Radius,
+			Solver:       datum.Solver,
+			TestParticle: datum.TestParticle,
+			Thrusting:    datum.Thrusting,
 		}
 	}
+	s.archive.Mergers = data.Mergers
+	s.archive.Impulses = data.Impulses
+	s.archive.Removed = data.Removed
+	s.history.record(&pb.Data{
+		Success:  s.archive.Success,
+		Content:  s.archive.Content,
+		Dt:       s.archive.Dt,
+		Time:     s.archive.Time,
+		Round:    s.archive.Round,
+		Mergers:  s.archive.Mergers,
+		Units:    s.archive.Units,
+		Impulses: s.archive.Impulses,
+		Removed:  s.archive.Removed,
+	})
+	subscriptions := make([]*subscription, 0, len(s.subscriptions))
+	for sub := range s.subscriptions {
+		subscriptions = append(subscriptions, sub)
+	}
 	s.mutex.Unlock()
-}
-
-func (s *server) resetConnectionsData() {
-	for _, c := range s.connections {
-		c.resetData()
+	// The archive is only written by the caller, lossless subscribers are waited for
+	// without holding the lock
+	for _, sub := range subscriptions {
+		sub.publish(&s.archive)
 	}
 }
 
-func (s *server) sendData(data *pb.Data) error {
-	for _, c := range s.connections {
+// A body that cannot be reached leaves the simulation, the others carry on
+func (s *server) sendData(members []*connection, data *pb.Data) {
+	for _, c := range members {
+		if c.owned != nil {
+			continue
+		}
 		err := c.stream.Send(data)
-		log.Printf("Sent data to %v: %v", c.name, data)
 		if err != nil {
-			return err
+			log.Printf("Removing %v from the simulation: %v", s.coordinator.getName(c.id), err)
+			s.coordinator.terminate(c, err)
+			continue
 		}
+		log.Printf("Sent data to %v: %v", s.coordinator.getName(c.id), data)
 	}
-	return nil
 }
 
-func (s *server) CelestialUpdate(stream pb.CelestialService_CelestialUpdateServer) error {
+// Ends the streams of the clients and of the subscribers with a last message telling
+// them the simulation is over, then stops the server
+func (s *server) shutdown() {
+	s.mutex.Lock()
+	data := pb.Data{
+		Success:  s.archive.Success,
+		Content:  make(map[string]*pb.CelestialBody),
+		Time:     s.archive.Time,
+		Round:    s.archive.Round,
+		Units:    s.units.Units,
+		Shutdown: true,
+	}
+	s.over = true
+	subscriptions := make([]*subscription, 0, len(s.subscriptions))
+	for sub := range s.subscriptions {
+		subscriptions = append(subscriptions, sub)
+	}
+	s.mutex.Unlock()
+	log.Printf("Shutting down the simulation after round %v", data.Round)
+	for _, c := range s.coordinator.remaining() {
+		if c.owned == nil {
+			if err := c.stream.Send(&data); err != nil {
+				log.Printf("Could not notify %v of the shutdown: %v", s.coordinator.getName(c.id), err)
+			}
+		}
+		s.coordinator.terminate(c, nil)
+	}
+	for _, sub := range subscriptions {
+		sub.enqueue(&pb.Data{Success: data.Success, Time: data.Time, Round: data.Round, Units: data.Units, Shutdown: true})
+	}
+	if s.stop != nil {
+		s.stop()
+	}
+}
 
-	log.Printf("New connection")
+// Runs the rounds until the coordinator is closed
+func (s *server) broadcastRounds() {
+	for {
+		members, bodies, owners, round, ok := s.coordinator.awaitRound()
+		if !ok {
+			s.shutdown()
+			return
+		}
+		data := pb.Data{
+			Success: true,
+			Content: make(map[string]*pb.CelestialBody),
+			Time:    s.time,
+			Round:   round,
+			Units:   s.units.Units,
+			Removed: s.removed,
+		}
+		s.removed = nil
+		members, bodies, owners = s.removeInvalidBodies(members, bodies, owners, &data)
+		absorbed, mergers := resolveCollisions(bodies)
+		data.Mergers = mergers
+		s.prepareBroadcastData(bodies, absorbed, &data)
+		data.Dt = s.negotiateStep(bodies)
+		data.Impulses = s.dueImpulses(&data)
+		if err := s.monitor.check(&data); err != nil {
+			log.Printf("Aborting the simulation: %v", err)
+			for _, c := range members {
+				s.coordinator.terminate(c, err)
+			}
+			// Bodies waiting to join and subscribers are told the simulation is over
+			s.coordinator.close()
+			s.shutdown()
+			return
+		}
+		// Keep an archive for frontend cilent through method CelestialBodiesPositions
+		s.archiveBroadcastData(&data)
+		if s.checkpointEvery > ZERO && round%s.checkpointEvery == ZERO {
+			if _, err := s.checkpoint(s.checkpointPath); err != nil {
+				log.Printf("Could not checkpoint round %v: %v", round, err)
+			}
+		}
+		// Absorbed bodies still receive the broadcast to learn about their merger
+		s.sendData(members, &data)
+		s.integrateOwnedBodies(owners, absorbed, &data)
+		// An absorbed body of a group is dropped by its client, the others end their stream
+		// and their group is removed with them from the next round
+		for i, body := range bodies {
+			if absorbed[i] && !body.grouped {
+				s.coordinator.terminate(owners[i], nil)
+				for j, member := range bodies {
+					if owners[j] == owners[i] && member.grouped && !absorbed[j] {
+						log.Printf("Removing %v along with %v", member.name, body.name)
+						s.removed = append(s.removed, member.name)
+					}
+				}
+			}
+		}
+		// Clients now integrate the snapshot over dt
+		s.time += data.Dt
+	}
+}
+
+// -------------------------------------------------------------------------------------
+
+// -------------------------------------------------------------------------------------
+// Server-owned bodies
+//
+// Bodies of a scenario marked "server" are integrated by the server itself with the same
+// solvers and force models as the clients, which spares a process per body for large
+// systems. Each one is a member of the rounds without a stream: once a round is broadcast,
+// the server integrates it over the step of the round and submits its next state, just
+// like its client would.
+
+type ownedBody struct {
+	name       string
+	solver     physics.Solver
+	forces     []physics.ForceModel
+	leaveRound uint64
+}
 
+func vectorOf(body *pb.CelestialBody) physics.Vector {
+	return physics.Vector{X: body.X, Y: body.Y, Z: body.Z, Vx: body.Vx, Vy: body.Vy, Vz: body.Vz}
+}
+
+// Next state of the body after the round of the broadcast, the bodies of the round being
+// given by field
+func (body *ownedBody) integrate(data *pb.Data, field *physics.RateFunctionHandler) (*pb.CelestialBody, error) {
+	self, ok := data.Content[body.name]
+	if !ok {
+		return nil, fmt.Errorf("%v is missing from the broadcast", body.name)
+	}
+	fh := field.For(body.name)
+	fh.Forces = body.forces
+	sigma := body.solver.Step(vectorOf(self), fh.Evaluate, data.Dt)
+	dt := self.Dt
+	if adaptive, ok := body.solver.(physics.AdaptiveSolver); ok {
+		dt = adaptive.ProposeStep(data.Dt)
+	}
+	return &pb.CelestialBody{
+		Sequence:     self.Sequence + ONE,
+		Name:         body.name,
+		Mass:         self.Mass,
+		X:            sigma.X,
+		Y:            sigma.Y,
+		Z:            sigma.Z,
+		Vx:           sigma.Vx,
+		Vy:           sigma.Vy,
+		Vz:           sigma.Vz,
+		Dt:           dt,
+		Radius:       self.Radius,
+		Solver:       self.Solver,
+		Units:        data.Units,
+		TestParticle: self.TestParticle,
+	}, nil
+}
+
+// Adds a body of a scenario to the simulation, integrated by the server
+func (s *server) own(body scenario.Body) error {
+	solver, err := physics.NewSolver(body.Solver, body.Tolerance)
+	if err != nil {
+		return fmt.Errorf("%v: %w", body.Name, err)
+	}
+	force := body.Force
+	if force == EMPTY_STR {
+		force = physics.NEWTON
+	}
+	primary := body.Primary
+	if primary == EMPTY_STR {
+		primary = body.Parent
+	}
+	forces, err := physics.NewForceModels(force, physics.ForceOptions{
+		Softening:     body.Softening,
+		Theta:         body.Theta,
+		Primary:       primary,
+		C:             s.units.C,
+		J2:            body.J2,
+		PrimaryRadius: body.PrimaryRadius,
+	})
+	if err != nil {
+		return fmt.Errorf("%v: %w", body.Name, err)
+	}
 	id, err := uuid.NewUUID()
 	if err != nil {
-		log.Printf("Error generating uuid %v", err)
 		return err
 	}
-	c := connection{
-		id:     id,
-		name:   "",
-		data:   lightCelestialBody{},
-		stream: stream,
+	c := newConnection(id, nil)
+	c.owned = &ownedBody{
+		name:       body.Name,
+		solver:     solver,
+		forces:     forces,
+		leaveRound: body.Leave,
 	}
+	s.coordinator.join(c)
+	return s.coordinator.submit(id, &pb.CelestialBody{
+		Sequence:     ONE,
+		Name:         body.Name,
+		Mass:         body.Mass,
+		X:            body.X,
+		Y:            body.Y,
+		Z:            body.Z,
+		Vx:           body.Vx,
+		Vy:           body.Vy,
+		Vz:           body.Vz,
+		Dt:           body.Dt,
+		JoinRound:    body.Join,
+		Radius:       body.Radius,
+		Solver:       body.Solver,
+		Units:        s.units.Units,
+		TestParticle: body.TestParticle,
+	})
+}
 
+// Integrates the server-owned members over the round and submits their next state. They
+// only read the broadcast, so they are integrated concurrently and share the state of the
+// bodies of the round.
+func (s *server) integrateOwnedBodies(owners []*connection, absorbed []bool, data *pb.Data) {
+	data = applyImpulses(data)
+	field := physics.RateFunctionHandler{G: s.units.G}
+	bodies := make([]physics.Body, 0, len(data.Content))
+	for name, body := range data.Content {
+		bodies = append(bodies, physics.Body{Name: name, Mass: body.Mass, State: vectorOf(body), TestParticle: body.TestParticle})
+	}
+	field.Update(bodies)
+	var wg sync.WaitGroup
+	for i, c := range owners {
+		if c.owned == nil || absorbed[i] {
+			continue
+		}
+		if c.owned.leaveRound > ZERO && data.Round >= c.owned.leaveRound {
+			log.Printf("%v leaves the simulation at round %v", c.owned.name, data.Round)
+			s.coordinator.terminate(c, nil)
+			continue
+		}
+		wg.Add(ONE)
+		go func(c *connection) {
+			defer wg.Done()
+			next, err := c.owned.integrate(data, &field)
+			if err == nil {
+				err = s.coordinator.submit(c.id, next)
+			}
+			if err != nil {
+				log.Printf("Removing %v from the simulation: %v", c.owned.name, err)
+				s.coordinator.terminate(c, err)
+			}
+		}(c)
+	}
+	wg.Wait()
+}
+
+// -------------------------------------------------------------------------------------
+
+// -------------------------------------------------------------------------------------
+// Impulses
+//
+// A maneuver changes the velocity of a body instantaneously. It is scheduled at a
+// sequence of the body or at a simulation time, and forwarded in the broadcast of the
+// first round reaching it. Whoever integrates a round applies its impulses to the states
+// of the broadcast beforehand, so that every client sees the same field.
+
+func (s *server) schedule(impulse *pb.Impulse) error {
+	if impulse.Name == EMPTY_STR {
+		return errors.New("impulse needs the name of a body")
+	}
+	for _, dv := range []float64{impulse.Dvx, impulse.Dvy, impulse.Dvz} {
+		if math.IsNaN(dv) || math.IsInf(dv, ZERO) {
+			return fmt.Errorf("invalid impulse %v", impulse)
+		}
+	}
 	s.mutex.Lock()
-	s.connections[id] = &c
-	n_connections := len(s.connections)
+	s.impulses = append(s.impulses, impulse)
 	s.mutex.Unlock()
+	return nil
+}
 
-	stop := make(chan bool)
-
-	if n_connections == ONE {
-		go func() {
-			for {
-				s.mutex.Lock()
-				isReadyForBroadcast := s.isReadyForBroadcast()
-				n_connections := len(s.connections)
-				s.mutex.Unlock()
-				if n_connections == ZERO {
-					break // Cancel goroutine if there are no clients
-				}
-				if !isReadyForBroadcast {
-					continue
-				}
-				// No mutex necessary as the clients are not sending anything
-				// (they are waiting for the broadcast!)
-				data := pb.Data{
-					Success: true,
-					Content: make(map[string]*pb.CelestialBody),
-				}
-				if err := s.prepareBroadcastData(&data); err != nil {
-					stop <- true
-					break
-				}
-				// Keep an archive for frontend cilent through method CelestialBodiesPositions
-				s.archiveBroadcastData(&data)
-				// Resetting connection's data first to ensure that data acquisition
-				// from clients can immediately resume
-				s.resetConnectionsData()
-				err := s.sendData(&data)
-				if err != nil {
-					stop <- true
-					break
-				}
-			}
-		}()
+func (s *server) pendingImpulses() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return uint64(len(s.impulses))
+}
+
+// Removes the impulses due in the round of data from the schedule. An impulse on a body
+// that is not part of the round waits for it to join
+func (s *server) dueImpulses(data *pb.Data) []*pb.Impulse {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	due := make([]*pb.Impulse, 0)
+	pending := make([]*pb.Impulse, 0, len(s.impulses))
+	for _, impulse := range s.impulses {
+		body, ok := data.Content[impulse.Name]
+		if ok && ((impulse.Sequence > ZERO && body.Sequence >= impulse.Sequence) || (impulse.Sequence == ZERO && data.Time >= impulse.Time)) {
+			log.Printf("Applying impulse (%v, %v, %v) to %v at round %v", impulse.Dvx, impulse.Dvy, impulse.Dvz, impulse.Name, data.Round)
+			due = append(due, impulse)
+			continue
+		}
+		pending = append(pending, impulse)
+	}
+	s.impulses = pending
+	return due
+}
+
+// Copy of the broadcast with its impulses applied, as the broadcast itself is sent already
+func applyImpulses(data *pb.Data) *pb.Data {
+	if len(data.Impulses) == ZERO {
+		return data
+	}
+	applied := proto.Clone(data).(*pb.Data)
+	for _, impulse := range applied.Impulses {
+		if body, ok := applied.Content[impulse.Name]; ok {
+			body.Vx += impulse.Dvx
+			body.Vy += impulse.Dvy
+			body.Vz += impulse.Dvz
+		}
+	}
+	return applied
+}
+
+// -------------------------------------------------------------------------------------
+
+// -------------------------------------------------------------------------------------
+// Checkpoints
+//
+// A checkpoint is the archived broadcast of a round in JSON: the state of every body at
+// the start of that round, with its sequence, step proposal and solver, along with the
+// round index, simulation time and step of the round. Restoring it replays that round,
+// with the impulses it was due along with those still scheduled.
+
+func (s *server) checkpoint(path string) (*pb.CheckpointReply, error) {
+	s.mutex.Lock()
+	if !s.archive.Success {
+		s.mutex.Unlock()
+		return nil, errors.New("no round was broadcast yet")
+	}
+	snapshot := proto.Clone(&s.archive).(*pb.Data)
+	snapshot.Scheduled = s.impulses
+	content, err := protojson.MarshalOptions{Multiline: true}.Marshal(snapshot)
+	reply := pb.CheckpointReply{Path: path, Round: s.archive.Round, Time: s.archive.Time}
+	s.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	// Write then rename so that a crash never leaves a truncated checkpoint behind
+	if err := os.WriteFile(path+".tmp", content, 0644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return nil, err
+	}
+	log.Printf("Checkpointed round %v to %v", reply.Round, path)
+	return &reply, nil
+}
+
+func loadCheckpoint(path string) (*pb.Data, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := pb.Data{}
+	if err := protojson.Unmarshal(content, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func (s *server) restore(snapshot *pb.Data) {
+	s.time = snapshot.Time
+	s.units = units.Of(snapshot.Units)
+	s.monitor.G = s.units.G
+	// The impulses due in the replayed round are due again
+	s.mutex.Lock()
+	s.impulses = append(append([]*pb.Impulse{}, snapshot.Impulses...), snapshot.Scheduled...)
+	s.mutex.Unlock()
+	s.removed = snapshot.Removed
+	s.coordinator.restore(snapshot)
+	s.archiveBroadcastData(snapshot)
+}
+
+func (s *server) Checkpoint(ctx context.Context, req *pb.CheckpointRequest) (*pb.CheckpointReply, error) {
+	log.Printf("Received Checkpoint call")
+	path := req.Path
+	if path == EMPTY_STR {
+		path = s.checkpointPath
+	}
+	return s.checkpoint(path)
+}
+
+// -------------------------------------------------------------------------------------
+
+func (s *server) CelestialUpdate(stream pb.CelestialService_CelestialUpdateServer) error {
+
+	log.Printf("New connection")
+
+	id, err := uuid.NewUUID()
+	if err != nil {
+		log.Printf("Error generating uuid %v", err)
+		return err
 	}
+	c := newConnection(id, stream)
+	s.coordinator.join(c)
+	defer s.coordinator.leave(id)
 
 	log.Printf("Initiating data reception for id %v", id)
-forloop:
-	for {
-		select {
-		case <-stop:
-			break forloop
-		default:
+	received := make(chan error, ONE)
+	go func() {
+		for {
 			data, err := stream.Recv()
 			if err != nil {
-				name := c.getName()
-				log.Printf("Could not receive data stream from %v", name)
-				s.mutex.Lock()
-				delete(s.connections, id)
-				s.mutex.Unlock()
-				return err
+				received <- err
+				return
 			}
-			s.mutex.Lock()
-			c.setName(data.Name)
-			c.setData(data)
-			log.Printf("Received data from %v: %v", c.name, c.data)
-			s.mutex.Unlock()
-
-			for {
-				if len(s.connections) == s.n {
-					break
-				}
+			if data.Units != s.units.Units {
+				received <- fmt.Errorf("%v uses %v units while the simulation uses %v units", data.Name, units.Of(data.Units).Name, s.units.Name)
+				return
+			}
+			if err := s.coordinator.submit(id, data); err != nil {
+				received <- err
+				return
 			}
 		}
+	}()
+
+	select {
+	case err := <-received:
+		if err == io.EOF {
+			log.Printf("%v left the simulation", s.coordinator.getName(id))
+			return nil
+		}
+		log.Printf("Could not receive data stream from %v: %v", s.coordinator.getName(id), err)
+		return err
+	case <-c.done:
+		return c.err
 	}
-	return nil
 }
 
 func (s *server) CelestialBodiesPositions(req *pb.CelestialBodiesPositionRequest, stream pb.CelestialService_CelestialBodiesPositionsServer) error {
-	log.Printf("Received CelestialBodiesPositions call")
+	log.Printf("Received CelestialBodiesPositions call: %v", req)
+	sub := newSubscription(req)
 
-	// Example of sending the latest data periodically
-	// You can adjust this logic based on how your data is generated or stored
-	ticker := time.NewTicker(time.Millisecond * 10)
-	defer ticker.Stop()
+	// The latest round is queued along with the registration, so that it is neither
+	// missed nor published a second time
+	s.mutex.Lock()
+	if s.over {
+		s.mutex.Unlock()
+		return errors.New("the simulation is over")
+	}
+	if s.archive.Success {
+		sub.publish(&s.archive)
+	}
+	s.subscriptions[sub] = true
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.subscriptions, sub)
+		s.mutex.Unlock()
+		close(sub.done)
+	}()
 
 	for {
 		select {
-		case <-ticker.C:
-			s.mutex.Lock()
-			if err := stream.Send(&s.archive); err != nil {
+		case view := <-sub.snapshots:
+			if err := stream.Send(view); err != nil {
 				log.Printf("Failed to send data: %v", err)
-				s.mutex.Unlock()
 				return err
 			}
-			s.mutex.Unlock()
+			if view.Shutdown {
+				return nil
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
 		}
 	}
 }
 
+func (s *server) History(req *pb.HistoryRequest, stream pb.CelestialService_HistoryServer) error {
+	log.Printf("Received History call: %v", req)
+	s.mutex.Lock()
+	rounds := s.history.query(req)
+	s.mutex.Unlock()
+	sub := newSubscription(&pb.CelestialBodiesPositionRequest{Names: req.Names, Frame: req.Frame})
+	for _, snapshot := range rounds {
+		view, err := sub.view(snapshot)
+		if err != nil {
+			log.Printf("Skipping snapshot: %v", err)
+			continue
+		}
+		if err := stream.Send(view); err != nil {
+			log.Printf("Failed to send data: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// -------------------------------------------------------------------------------------
+
+// -------------------------------------------------------------------------------------
+// Control
+
+type controlServer struct {
+	pb.UnimplementedControlServiceServer
+	server *server
+}
+
+// Completes the reply of the coordinator with the impulses not due yet
+func (cs *controlServer) reply(reply *pb.ControlReply) *pb.ControlReply {
+	reply.Impulses = cs.server.pendingImpulses()
+	return reply
+}
+
+func (cs *controlServer) Pause(ctx context.Context, req *pb.ControlRequest) (*pb.ControlReply, error) {
+	reply := cs.server.coordinator.pause(true)
+	log.Printf("Pausing the simulation before round %v", reply.Round)
+	return cs.reply(reply), nil
+}
+
+func (cs *controlServer) Resume(ctx context.Context, req *pb.ControlRequest) (*pb.ControlReply, error) {
+	reply := cs.server.coordinator.pause(false)
+	log.Printf("Resuming the simulation at round %v", reply.Round)
+	return cs.reply(reply), nil
+}
+
+func (cs *controlServer) StepN(ctx context.Context, req *pb.StepRequest) (*pb.ControlReply, error) {
+	if req.N == ZERO {
+		return nil, errors.New("n must be positive")
+	}
+	reply := cs.server.coordinator.step(req.N)
+	log.Printf("Stepping the simulation %v rounds from round %v", reply.Steps, reply.Round)
+	return cs.reply(reply), nil
+}
+
+// The shutdown itself happens once the round being broadcast, if any, is over
+func (cs *controlServer) Shutdown(ctx context.Context, req *pb.ControlRequest) (*pb.ControlReply, error) {
+	cs.server.coordinator.close()
+	return cs.reply(cs.server.coordinator.report()), nil
+}
+
+func (cs *controlServer) ScheduleImpulse(ctx context.Context, req *pb.Impulse) (*pb.ControlReply, error) {
+	if err := cs.server.schedule(req); err != nil {
+		return nil, err
+	}
+	log.Printf("Scheduled impulse %v", req)
+	return cs.reply(cs.server.coordinator.report()), nil
+}
+
+// -------------------------------------------------------------------------------------
+
 func main() {
 	parser := argParser{}
 	parser.parse()
@@ -284,17 +1527,46 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to start listener on port %v", port)
 	}
+	system, err := units.Parse(parser.units)
+	if err != nil {
+		log.Fatalf("Failed to select unit system: %v", err)
+	}
+	celestialServer := newServer(n, parser.dt, system)
+	celestialServer.checkpointPath = parser.checkpoint
+	celestialServer.checkpointEvery = parser.checkpointEvery
+	celestialServer.monitor.maxDrift = parser.maxDrift
+	celestialServer.history = newHistory(parser.history)
+	if parser.restore != EMPTY_STR {
+		snapshot, err := loadCheckpoint(parser.restore)
+		if err != nil {
+			log.Fatalf("Failed to restore checkpoint %v: %v", parser.restore, err)
+		}
+		celestialServer.restore(snapshot)
+		log.Printf("Restored round %v at time %v in %v units, waiting for %v bodies to reattach", snapshot.Round, snapshot.Time, celestialServer.units.Name, len(snapshot.Content))
+	}
+	if parser.scenario != EMPTY_STR {
+		sc, err := scenario.Load(parser.scenario)
+		if err != nil {
+			log.Fatalf("Failed to load scenario: %v", err)
+		}
+		if system, _ := units.Parse(sc.Units); system.Units != celestialServer.units.Units {
+			log.Fatalf("Scenario %v is in %v units while the simulation uses %v units", parser.scenario, sc.Units, celestialServer.units.Name)
+		}
+		for _, body := range sc.ServerBodies() {
+			if err := celestialServer.own(body); err != nil {
+				log.Fatalf("Failed to integrate %v on the server: %v", body.Name, err)
+			}
+			log.Printf("%v is integrated by the server", body.Name)
+		}
+	}
 	s := grpc.NewServer()
-	pb.RegisterCelestialServiceServer(s, &server{
-		connections: make(map[uuid.UUID]*connection),
-		archive: pb.Data{
-			Success: false,
-			Content: make(map[string]*pb.CelestialBody),
-		},
-		n: n,
-	})
+	celestialServer.stop = s.GracefulStop
+	go celestialServer.broadcastRounds()
+	pb.RegisterCelestialServiceServer(s, celestialServer)
+	pb.RegisterControlServiceServer(s, &controlServer{server: celestialServer})
 	log.Printf("CelestialService started on port %v", port)
 	if err = s.Serve(lis); err != nil {
 		log.Fatalf("Failed to start grpc server on port %v", port)
 	}
+	log.Printf("CelestialService stopped")
 }
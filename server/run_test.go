@@ -0,0 +1,702 @@
+package main
+
+import (
+	"context"
+	"io"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "taiyoukei/proto"
+	"taiyoukei/scenario"
+	"taiyoukei/units"
+
+	"google.golang.org/grpc"
+)
+
+const TIMEOUT = 2 * time.Second
+
+// In-memory stand-in for the CelestialUpdate stream of a client
+type fakeStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	cancel context.CancelFunc
+	in     chan *pb.CelestialBody
+	out    chan *pb.Data
+}
+
+func newFakeStream() *fakeStream {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &fakeStream{
+		ctx:    ctx,
+		cancel: cancel,
+		in:     make(chan *pb.CelestialBody),
+		out:    make(chan *pb.Data, 16),
+	}
+}
+
+func (fs *fakeStream) Context() context.Context {
+	return fs.ctx
+}
+
+func (fs *fakeStream) Recv() (*pb.CelestialBody, error) {
+	select {
+	case data, ok := <-fs.in:
+		if !ok {
+			return nil, io.EOF
+		}
+		return data, nil
+	case <-fs.ctx.Done():
+		return nil, fs.ctx.Err()
+	}
+}
+
+func (fs *fakeStream) Send(data *pb.Data) error {
+	fs.out <- data
+	return nil
+}
+
+func (fs *fakeStream) send(t *testing.T, data *pb.CelestialBody) {
+	t.Helper()
+	select {
+	case fs.in <- data:
+	case <-time.After(TIMEOUT):
+		t.Fatalf("server did not receive %v", data.Name)
+	}
+}
+
+func (fs *fakeStream) receive(t *testing.T) *pb.Data {
+	t.Helper()
+	select {
+	case data := <-fs.out:
+		return data
+	case <-time.After(TIMEOUT):
+		t.Fatalf("no broadcast received")
+	}
+	return nil
+}
+
+// Starts a server and connects one fake client per name
+func startServer(t *testing.T, n int, dt float64, names ...string) (*server, []*fakeStream, []chan error) {
+	t.Helper()
+	s := newServer(n, dt, units.Of(pb.Units_CANONICAL))
+	go s.broadcastRounds()
+	t.Cleanup(s.coordinator.close)
+	streams := make([]*fakeStream, len(names))
+	results := make([]chan error, len(names))
+	for i := range names {
+		streams[i] = newFakeStream()
+		results[i] = make(chan error, 1)
+		go func(i int) {
+			results[i] <- s.CelestialUpdate(streams[i])
+		}(i)
+		t.Cleanup(streams[i].cancel)
+	}
+	return s, streams, results
+}
+
+func TestRoundIsBroadcastOnceAllBodiesReported(t *testing.T) {
+	_, streams, _ := startServer(t, 2, 0.5, "A", "B")
+
+	streams[0].send(t, &pb.CelestialBody{Sequence: 1, Name: "A", Mass: 1, X: 1})
+	select {
+	case <-streams[0].out:
+		t.Fatalf("broadcast before all bodies reported")
+	case <-time.After(50 * time.Millisecond):
+	}
+	streams[1].send(t, &pb.CelestialBody{Sequence: 1, Name: "B", Mass: 2, X: -1})
+
+	for _, stream := range streams {
+		data := stream.receive(t)
+		if len(data.Content) != 2 {
+			t.Fatalf("expected 2 bodies, got %v", data.Content)
+		}
+		if data.Dt != 0.5 || data.Time != 0 {
+			t.Fatalf("expected dt 0.5 at time 0, got dt %v at time %v", data.Dt, data.Time)
+		}
+	}
+}
+
+func TestRoundUsesSmallestProposedStep(t *testing.T) {
+	_, streams, _ := startServer(t, 2, 0.5, "A", "B")
+
+	for round := 0; round < 3; round++ {
+		streams[0].send(t, &pb.CelestialBody{Sequence: uint64(round), Name: "A", Mass: 1, X: 1, Dt: 0.1})
+		streams[1].send(t, &pb.CelestialBody{Sequence: uint64(round), Name: "B", Mass: 1, X: -1, Dt: 0.25})
+		for _, stream := range streams {
+			data := stream.receive(t)
+			if data.Dt != 0.1 {
+				t.Fatalf("expected dt 0.1, got %v", data.Dt)
+			}
+			if math.Abs(data.Time-0.1*float64(round)) > 1e-12 {
+				t.Fatalf("expected time %v, got %v", 0.1*float64(round), data.Time)
+			}
+		}
+	}
+}
+
+func TestNaNStateRemovesOnlyThatBody(t *testing.T) {
+	_, streams, results := startServer(t, 2, 0.5, "A", "B")
+
+	streams[0].send(t, &pb.CelestialBody{Sequence: 1, Name: "A", Mass: 1, X: math.NaN()})
+	streams[1].send(t, &pb.CelestialBody{Sequence: 1, Name: "B", Mass: 1, X: -1})
+
+	select {
+	case err := <-results[0]:
+		if err == nil {
+			t.Fatalf("expected an error")
+		}
+	case <-time.After(TIMEOUT):
+		t.Fatalf("connection was not terminated")
+	}
+	data := streams[1].receive(t)
+	if _, ok := data.Content["A"]; ok || len(data.Content) != 1 {
+		t.Fatalf("expected only B in the broadcast, got %v", data.Content)
+	}
+}
+
+func TestLeavingBodyIsDroppedFromTheRound(t *testing.T) {
+	_, streams, results := startServer(t, 2, 0.5, "A", "B")
+
+	streams[0].send(t, &pb.CelestialBody{Sequence: 1, Name: "A", Mass: 1, X: 1})
+	streams[1].send(t, &pb.CelestialBody{Sequence: 1, Name: "B", Mass: 1, X: -1})
+	streams[0].receive(t)
+	streams[1].receive(t)
+
+	close(streams[1].in)
+	if err := <-results[1]; err != nil {
+		t.Fatalf("expected a clean leave, got %v", err)
+	}
+	streams[0].send(t, &pb.CelestialBody{Sequence: 2, Name: "A", Mass: 1, X: 1})
+	data := streams[0].receive(t)
+	if len(data.Content) != 1 || data.Round != 1 {
+		t.Fatalf("expected round 1 with A only, got %v", data)
+	}
+}
+
+func TestBodyJoinsAtRequestedRound(t *testing.T) {
+	_, streams, _ := startServer(t, 1, 0.5, "A", "C")
+
+	streams[1].send(t, &pb.CelestialBody{Sequence: 1, Name: "C", Mass: 1, X: 5, JoinRound: 2})
+	for round := uint64(0); round < 3; round++ {
+		streams[0].send(t, &pb.CelestialBody{Sequence: round + 1, Name: "A", Mass: 1, X: 1})
+		data := streams[0].receive(t)
+		_, joined := data.Content["C"]
+		if joined != (round >= 2) {
+			t.Fatalf("round %v: unexpected membership %v", round, data.Content)
+		}
+	}
+	if data := streams[1].receive(t); data.Round != 2 {
+		t.Fatalf("expected C to receive round 2 first, got %v", data.Round)
+	}
+}
+
+func TestCollidingBodiesMerge(t *testing.T) {
+	_, streams, results := startServer(t, 3, 0.5, "A", "B", "C")
+
+	streams[0].send(t, &pb.CelestialBody{Sequence: 1, Name: "A", Mass: 3, X: 0, Vx: 1, Radius: 0.1})
+	streams[1].send(t, &pb.CelestialBody{Sequence: 1, Name: "B", Mass: 1, X: 0.15, Vx: -1, Radius: 0.1})
+	streams[2].send(t, &pb.CelestialBody{Sequence: 1, Name: "C", Mass: 1, X: 5})
+
+	data := streams[0].receive(t)
+	if len(data.Mergers) != 1 || data.Mergers[0].Absorbed != "B" || data.Mergers[0].Survivor != "A" {
+		t.Fatalf("expected B to merge into A, got %v", data.Mergers)
+	}
+	merged, ok := data.Content["A"]
+	if !ok || len(data.Content) != 2 {
+		t.Fatalf("expected A and C only, got %v", data.Content)
+	}
+	if merged.Mass != 4 || math.Abs(merged.Vx-0.5) > 1e-12 || math.Abs(merged.X-0.0375) > 1e-12 {
+		t.Fatalf("merger does not conserve mass and momentum: %v", merged)
+	}
+	streams[1].receive(t)
+	if err := <-results[1]; err != nil {
+		t.Fatalf("expected the absorbed body to end cleanly, got %v", err)
+	}
+}
+
+func TestCheckpointIsRestoredByName(t *testing.T) {
+	s, streams, _ := startServer(t, 2, 0.5, "A", "B")
+	for _, impulse := range []*pb.Impulse{{Name: "B", Dvy: 1, Sequence: 2}, {Name: "A", Dvx: 3, Time: 10}} {
+		if err := s.schedule(impulse); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for round := uint64(0); round < 2; round++ {
+		streams[0].send(t, &pb.CelestialBody{Sequence: round + 1, Name: "A", Mass: 1, X: 1 + float64(round), Solver: "rk4"})
+		streams[1].send(t, &pb.CelestialBody{Sequence: round + 1, Name: "B", Mass: 2, X: -1})
+		streams[0].receive(t)
+		streams[1].receive(t)
+	}
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	reply, err := s.checkpoint(path)
+	if err != nil {
+		t.Fatalf("checkpoint failed: %v", err)
+	}
+	if reply.Round != 1 || reply.Time != 0.5 {
+		t.Fatalf("unexpected checkpoint %v", reply)
+	}
+
+	snapshot, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("could not load checkpoint: %v", err)
+	}
+	restored, streams, _ := startServer(t, 5, 0.5, "A", "B")
+	restored.restore(snapshot)
+	streams[0].send(t, &pb.CelestialBody{Sequence: 1, Name: "A", Mass: 1, X: 42})
+	streams[1].send(t, &pb.CelestialBody{Sequence: 1, Name: "B", Mass: 1, X: 42})
+	data := streams[0].receive(t)
+	if data.Round != 1 || data.Time != 0.5 {
+		t.Fatalf("expected to replay round 1 at time 0.5, got round %v at time %v", data.Round, data.Time)
+	}
+	if a := data.Content["A"]; a.X != 2 || a.Sequence != 2 || a.Solver != "rk4" {
+		t.Fatalf("A did not get its checkpointed state: %v", a)
+	}
+	if b := data.Content["B"]; b.X != -1 || b.Mass != 2 {
+		t.Fatalf("B did not get its checkpointed state: %v", b)
+	}
+	// The impulse due in the replayed round is forwarded again, the other one still waits
+	if len(data.Impulses) != 1 || data.Impulses[0].Name != "B" || restored.pendingImpulses() != 1 {
+		t.Fatalf("expected the impulses to be restored, got %v and %v pending", data.Impulses, restored.pendingImpulses())
+	}
+}
+
+func TestDriftAbortsTheSimulation(t *testing.T) {
+	s, streams, results := startServer(t, 2, 0.5, "A", "B")
+	s.monitor.maxDrift = 1e-3
+	stopped := make(chan struct{})
+	s.stop = func() { close(stopped) }
+	subscriber := &fakePositionsStream{ctx: context.Background(), out: make(chan *pb.Data, 8)}
+	go s.CelestialBodiesPositions(&pb.CelestialBodiesPositionRequest{}, subscriber)
+
+	streams[0].send(t, &pb.CelestialBody{Sequence: 1, Name: "A", Mass: 1, X: 1, Vy: 0.5})
+	streams[1].send(t, &pb.CelestialBody{Sequence: 1, Name: "B", Mass: 1, X: -1, Vy: -0.5})
+	streams[0].receive(t)
+	streams[1].receive(t)
+	// Speeds doubled without the bodies getting closer: energy is not conserved
+	streams[0].send(t, &pb.CelestialBody{Sequence: 2, Name: "A", Mass: 1, X: 1, Vy: 1})
+	streams[1].send(t, &pb.CelestialBody{Sequence: 2, Name: "B", Mass: 1, X: -1, Vy: -1})
+
+	for _, result := range results {
+		select {
+		case err := <-result:
+			if err == nil {
+				t.Fatalf("expected the drift to abort the simulation")
+			}
+		case <-time.After(TIMEOUT):
+			t.Fatalf("simulation was not aborted")
+		}
+	}
+	for shutdown := false; !shutdown; {
+		select {
+		case data := <-subscriber.out:
+			shutdown = data.Shutdown
+		case <-time.After(TIMEOUT):
+			t.Fatalf("subscriber was not told about the abort")
+		}
+	}
+	select {
+	case <-stopped:
+	case <-time.After(TIMEOUT):
+		t.Fatalf("server was not stopped")
+	}
+}
+
+func TestThrustDoesNotAbortTheSimulation(t *testing.T) {
+	s, streams, _ := startServer(t, 2, 0.5, "A", "B")
+	s.monitor.maxDrift = 1e-3
+
+	streams[0].send(t, &pb.CelestialBody{Sequence: 1, Name: "A", Mass: 1, X: 1, Vy: 0.5})
+	streams[1].send(t, &pb.CelestialBody{Sequence: 1, Name: "B", Mass: 1, X: -1, Vy: -0.5})
+	streams[0].receive(t)
+	streams[1].receive(t)
+	// The energy gained is the work of the thrust
+	streams[0].send(t, &pb.CelestialBody{Sequence: 2, Name: "A", Mass: 1, X: 1, Vy: 1, Thrusting: true})
+	streams[1].send(t, &pb.CelestialBody{Sequence: 2, Name: "B", Mass: 1, X: -1, Vy: -0.5})
+	if data := streams[0].receive(t); !data.Content["A"].Thrusting {
+		t.Fatalf("expected A to be broadcast as thrusting, got %v", data.Content["A"])
+	}
+	streams[1].receive(t)
+}
+
+func TestServerOwnedBodyIsIntegrated(t *testing.T) {
+	s, streams, _ := startServer(t, 2, 0.5, "A")
+	if err := s.own(scenario.Body{Name: "B", Mass: 1, Vx: 1, Solver: "rk4", Dt: 0.5}); err != nil {
+		t.Fatalf("could not own B: %v", err)
+	}
+
+	// A is massless so that B moves in a straight line
+	streams[0].send(t, &pb.CelestialBody{Sequence: 1, Name: "A", X: 10})
+	if data := streams[0].receive(t); data.Content["B"].X != 0 {
+		t.Fatalf("expected B at its initial state, got %v", data.Content["B"])
+	}
+	streams[0].send(t, &pb.CelestialBody{Sequence: 2, Name: "A", X: 10})
+	data := streams[0].receive(t)
+	b := data.Content["B"]
+	if b.Sequence != 2 || math.Abs(b.X-0.5) > 1e-12 || b.Vx != 1 {
+		t.Fatalf("B was not integrated over the round: %v", b)
+	}
+}
+
+func TestTestParticlesShareTheStreamOfABody(t *testing.T) {
+	_, streams, _ := startServer(t, 4, 0.5, "A", "B")
+
+	streams[0].send(t, &pb.CelestialBody{Sequence: 1, Name: "A", Mass: 1, Radius: 0.1, Group: []*pb.CelestialBody{
+		{Sequence: 1, Name: "P1", X: 0.05, Radius: 0.1, TestParticle: true},
+		{Sequence: 1, Name: "P2", X: 3, Mass: 5, TestParticle: true},
+	}})
+	select {
+	case <-streams[0].out:
+		t.Fatalf("broadcast before all bodies reported")
+	case <-time.After(50 * time.Millisecond):
+	}
+	streams[1].send(t, &pb.CelestialBody{Sequence: 1, Name: "B", Mass: 1, X: -1})
+
+	data := streams[0].receive(t)
+	if len(data.Mergers) != 1 || data.Mergers[0].Absorbed != "P1" || data.Mergers[0].Survivor != "A" {
+		t.Fatalf("expected A to absorb P1, got %v", data.Mergers)
+	}
+	if a := data.Content["A"]; a.Mass != 1 || a.Radius != 0.1 {
+		t.Fatalf("a test particle changed the body it hit: %v", a)
+	}
+	if p2, ok := data.Content["P2"]; !ok || !p2.TestParticle || len(data.Content) != 3 {
+		t.Fatalf("expected A, B and P2, got %v", data.Content)
+	}
+}
+
+func TestGroupIsIntegratedByOneStream(t *testing.T) {
+	_, streams, results := startServer(t, 3, 0.5, "E", "S")
+
+	streams[0].send(t, &pb.CelestialBody{Sequence: 1, Name: "E", Mass: 0.1, X: 1, Dt: 0.25, Group: []*pb.CelestialBody{
+		{Sequence: 1, Name: "M", Mass: 0.01, X: 1.1, Dt: 0.1},
+	}})
+	streams[1].send(t, &pb.CelestialBody{Sequence: 1, Name: "S", Mass: 1})
+
+	data := streams[0].receive(t)
+	if m, ok := data.Content["M"]; !ok || m.Mass != 0.01 || len(data.Content) != 3 {
+		t.Fatalf("expected S, E and M, got %v", data.Content)
+	}
+	if data.Dt != 0.1 {
+		t.Fatalf("expected the step proposed by M, got %v", data.Dt)
+	}
+	// The group leaves with its body
+	streams[1].receive(t)
+	close(streams[0].in)
+	if err := <-results[0]; err != nil {
+		t.Fatalf("expected a clean leave, got %v", err)
+	}
+	streams[1].send(t, &pb.CelestialBody{Sequence: 2, Name: "S", Mass: 1})
+	if data := streams[1].receive(t); len(data.Content) != 1 {
+		t.Fatalf("expected S only, got %v", data.Content)
+	}
+}
+
+func TestGroupIsRemovedWithItsBody(t *testing.T) {
+	// Absorbed by S, E takes M along from the next round
+	_, streams, results := startServer(t, 3, 0.5, "E", "S")
+	streams[0].send(t, &pb.CelestialBody{Sequence: 1, Name: "E", Mass: 0.1, X: 0.5, Radius: 0.1, Group: []*pb.CelestialBody{
+		{Sequence: 1, Name: "M", Mass: 0.01, X: 5},
+	}})
+	streams[1].send(t, &pb.CelestialBody{Sequence: 1, Name: "S", Mass: 1, Radius: 1})
+	if data := streams[1].receive(t); len(data.Mergers) != 1 || len(data.Removed) != 0 {
+		t.Fatalf("expected E to be absorbed, got %v", data)
+	}
+	if err := <-results[0]; err != nil {
+		t.Fatalf("expected the absorbed body to end its stream, got %v", err)
+	}
+	streams[1].send(t, &pb.CelestialBody{Sequence: 2, Name: "S", Mass: 1.1, Radius: 1})
+	data := streams[1].receive(t)
+	if _, ok := data.Content["M"]; ok || len(data.Removed) != 1 || data.Removed[0] != "M" {
+		t.Fatalf("expected M to be removed, got %v", data)
+	}
+
+	// Invalid, E takes M along in the same round
+	_, streams, results = startServer(t, 3, 0.5, "E", "S")
+	streams[0].send(t, &pb.CelestialBody{Sequence: 1, Name: "E", Mass: 0.1, X: math.NaN(), Group: []*pb.CelestialBody{
+		{Sequence: 1, Name: "M", Mass: 0.01, X: 5},
+	}})
+	streams[1].send(t, &pb.CelestialBody{Sequence: 1, Name: "S", Mass: 1})
+	if err := <-results[0]; err == nil {
+		t.Fatalf("expected an error")
+	}
+	data = streams[1].receive(t)
+	if len(data.Content) != 1 || len(data.Removed) != 2 {
+		t.Fatalf("expected E and M to be removed, got %v", data)
+	}
+}
+
+func TestSubscriptionFiltersTheSnapshot(t *testing.T) {
+	snapshot := &pb.Data{Success: true, Round: 4, Content: map[string]*pb.CelestialBody{
+		"S": {Name: "S", Mass: 3, X: 1, Vy: 1},
+		"E": {Name: "E", Mass: 1, X: 5, Vy: 2},
+		"P": {Name: "P", Mass: 1, X: 9, TestParticle: true},
+	}}
+
+	sub := newSubscription(&pb.CelestialBodiesPositionRequest{Names: []string{"E"}, Decimation: 2, Frame: "S"})
+	if !sub.wants(4) || sub.wants(5) {
+		t.Fatalf("expected every other round")
+	}
+	view, err := sub.view(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := view.Content["E"]; !ok || len(view.Content) != 1 || e.X != 4 || e.Vy != 1 || view.Frame != "S" {
+		t.Fatalf("expected E relative to S only, got %v", view)
+	}
+	if snapshot.Content["E"].X != 5 {
+		t.Fatalf("the view modified the snapshot")
+	}
+
+	// The test particle does not move the barycenter
+	sub = newSubscription(&pb.CelestialBodiesPositionRequest{Frame: BARYCENTER})
+	if view, err = sub.view(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if len(view.Content) != 3 || view.Content["S"].X != -1 || view.Content["E"].Vy != 0.75 {
+		t.Fatalf("expected the states relative to the barycenter, got %v", view)
+	}
+
+	sub = newSubscription(&pb.CelestialBodiesPositionRequest{Frame: "M"})
+	if _, err := sub.view(snapshot); err == nil {
+		t.Fatalf("expected an error for a frame missing from the round")
+	}
+}
+
+// In-memory stand-in for the CelestialBodiesPositions stream of a subscriber
+type fakePositionsStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	out chan *pb.Data
+}
+
+func (fs *fakePositionsStream) Context() context.Context {
+	return fs.ctx
+}
+
+func (fs *fakePositionsStream) Send(data *pb.Data) error {
+	fs.out <- data
+	return nil
+}
+
+func TestEveryRoundIsPublishedOnce(t *testing.T) {
+	s, streams, _ := startServer(t, 1, 0.5, "A")
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	// Unbuffered, so that the subscriber lags behind the rounds
+	subscriber := &fakePositionsStream{ctx: ctx, out: make(chan *pb.Data)}
+	go s.CelestialBodiesPositions(&pb.CelestialBodiesPositionRequest{Lossless: true, Buffer: 1}, subscriber)
+	for subscribed := false; !subscribed; time.Sleep(time.Millisecond) {
+		s.mutex.Lock()
+		subscribed = len(s.subscriptions) > 0
+		s.mutex.Unlock()
+	}
+
+	const ROUNDS = 5
+	go func() {
+		for round := uint64(0); round < ROUNDS; round++ {
+			streams[0].in <- &pb.CelestialBody{Sequence: round + 1, Name: "A", Mass: 1}
+			<-streams[0].out
+		}
+	}()
+	for round := uint64(0); round < ROUNDS; round++ {
+		select {
+		case data := <-subscriber.out:
+			if data.Round != round || data.Dropped != 0 {
+				t.Fatalf("expected round %v, got %v", round, data)
+			}
+		case <-time.After(TIMEOUT):
+			t.Fatalf("round %v was not published", round)
+		}
+	}
+	select {
+	case data := <-subscriber.out:
+		t.Fatalf("unexpected snapshot %v", data)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSubscriptionBufferIsBounded(t *testing.T) {
+	sub := newSubscription(&pb.CelestialBodiesPositionRequest{Buffer: math.MaxUint32})
+	if cap(sub.snapshots) != MAX_SUBSCRIPTION_BUFFER {
+		t.Fatalf("expected a queue of %v snapshots, got %v", MAX_SUBSCRIPTION_BUFFER, cap(sub.snapshots))
+	}
+}
+
+func TestSlowSubscriberDropsTheOldestSnapshot(t *testing.T) {
+	sub := newSubscription(&pb.CelestialBodiesPositionRequest{Buffer: 2})
+	for round := uint64(0); round < 4; round++ {
+		sub.publish(&pb.Data{Success: true, Round: round})
+	}
+	for _, expected := range []*pb.Data{{Round: 2, Dropped: 1}, {Round: 3, Dropped: 2}} {
+		data := <-sub.snapshots
+		if data.Round != expected.Round || data.Dropped != expected.Dropped {
+			t.Fatalf("expected round %v after %v drops, got %v", expected.Round, expected.Dropped, data)
+		}
+	}
+}
+
+func TestHistoryReturnsTheKeptRounds(t *testing.T) {
+	s, streams, _ := startServer(t, 2, 0.5, "A", "B")
+	s.history = newHistory(3)
+	for round := uint64(0); round < 5; round++ {
+		streams[0].send(t, &pb.CelestialBody{Sequence: round + 1, Name: "A", Mass: 1, X: 1})
+		streams[1].send(t, &pb.CelestialBody{Sequence: round + 1, Name: "B", Mass: 1, X: -1})
+		streams[0].receive(t)
+		streams[1].receive(t)
+	}
+
+	query := func(req *pb.HistoryRequest) []*pb.Data {
+		t.Helper()
+		stream := &fakePositionsStream{ctx: context.Background(), out: make(chan *pb.Data, 8)}
+		if err := s.History(req, stream); err != nil {
+			t.Fatal(err)
+		}
+		close(stream.out)
+		rounds := make([]*pb.Data, 0)
+		for data := range stream.out {
+			rounds = append(rounds, data)
+		}
+		return rounds
+	}
+	// Rounds 0 and 1 were overwritten
+	rounds := query(&pb.HistoryRequest{Names: []string{"A"}, Frame: "B"})
+	if len(rounds) != 3 || rounds[0].Round != 2 || rounds[2].Round != 4 {
+		t.Fatalf("expected rounds 2 to 4, got %v", rounds)
+	}
+	if a := rounds[0].Content["A"]; len(rounds[0].Content) != 1 || a.X != 2 {
+		t.Fatalf("expected A relative to B, got %v", rounds[0].Content)
+	}
+	if rounds := query(&pb.HistoryRequest{FromRound: 3, ToRound: 3}); len(rounds) != 1 || rounds[0].Round != 3 {
+		t.Fatalf("expected round 3, got %v", rounds)
+	}
+	from, to := 1.25, 2.0
+	if rounds := query(&pb.HistoryRequest{FromTime: &from, ToTime: &to}); len(rounds) != 2 || rounds[0].Time != 1.5 {
+		t.Fatalf("expected the rounds at times 1.5 and 2, got %v", rounds)
+	}
+	// Either bound alone restricts the range, and a range may end at time 0
+	if rounds := query(&pb.HistoryRequest{FromTime: &to}); len(rounds) != 1 || rounds[0].Time != 2 {
+		t.Fatalf("expected the round at time 2, got %v", rounds)
+	}
+	zero := 0.0
+	if rounds := query(&pb.HistoryRequest{ToTime: &zero}); len(rounds) != 0 {
+		t.Fatalf("expected no round up to time 0, got %v", rounds)
+	}
+}
+
+func TestPausedRoundsOnlyAdvanceByStep(t *testing.T) {
+	s, streams, _ := startServer(t, 1, 0.5, "A")
+	control := &controlServer{server: s}
+	if _, err := control.Pause(context.Background(), &pb.ControlRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	expectRound := func(round uint64) {
+		t.Helper()
+		streams[0].send(t, &pb.CelestialBody{Sequence: round + 1, Name: "A", Mass: 1})
+		if data := streams[0].receive(t); data.Round != round {
+			t.Fatalf("expected round %v, got %v", round, data.Round)
+		}
+	}
+
+	streams[0].send(t, &pb.CelestialBody{Sequence: 1, Name: "A", Mass: 1})
+	select {
+	case data := <-streams[0].out:
+		t.Fatalf("broadcast while paused: %v", data)
+	case <-time.After(50 * time.Millisecond):
+	}
+	reply, err := control.StepN(context.Background(), &pb.StepRequest{N: 2})
+	if err != nil || !reply.Paused || reply.Steps != 2 {
+		t.Fatalf("expected 2 steps, got %v %v", reply, err)
+	}
+	if data := streams[0].receive(t); data.Round != 0 {
+		t.Fatalf("expected round 0, got %v", data.Round)
+	}
+	expectRound(1)
+
+	streams[0].send(t, &pb.CelestialBody{Sequence: 3, Name: "A", Mass: 1})
+	select {
+	case data := <-streams[0].out:
+		t.Fatalf("broadcast after the steps: %v", data)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if _, err := control.Resume(context.Background(), &pb.ControlRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if data := streams[0].receive(t); data.Round != 2 {
+		t.Fatalf("expected round 2, got %v", data.Round)
+	}
+	expectRound(3)
+}
+
+func TestShutdownNotifiesClientsAndSubscribers(t *testing.T) {
+	s, streams, results := startServer(t, 1, 0.5, "A")
+	stopped := make(chan struct{})
+	s.stop = func() { close(stopped) }
+	subscriber := &fakePositionsStream{ctx: context.Background(), out: make(chan *pb.Data, 8)}
+	subscribed := make(chan error, 1)
+	go func() {
+		subscribed <- s.CelestialBodiesPositions(&pb.CelestialBodiesPositionRequest{}, subscriber)
+	}()
+
+	streams[0].send(t, &pb.CelestialBody{Sequence: 1, Name: "A", Mass: 1})
+	streams[0].receive(t)
+	if data := <-subscriber.out; data.Round != 0 {
+		t.Fatalf("expected round 0, got %v", data)
+	}
+	if _, err := (&controlServer{server: s}).Shutdown(context.Background(), &pb.ControlRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if data := streams[0].receive(t); !data.Shutdown {
+		t.Fatalf("expected a shutdown message, got %v", data)
+	}
+	if err := <-results[0]; err != nil {
+		t.Fatalf("expected a clean end of the stream, got %v", err)
+	}
+	if data := <-subscriber.out; !data.Shutdown {
+		t.Fatalf("expected a shutdown message, got %v", data)
+	}
+	if err := <-subscribed; err != nil {
+		t.Fatalf("expected a clean end of the subscription, got %v", err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(TIMEOUT):
+		t.Fatalf("server was not stopped")
+	}
+}
+
+func TestImpulsesAreForwardedWhenDue(t *testing.T) {
+	s, streams, _ := startServer(t, 2, 0.5, "A")
+	if err := s.own(scenario.Body{Name: "B", Mass: 1, Vx: 1, Solver: "rk4", Dt: 0.5}); err != nil {
+		t.Fatalf("could not own B: %v", err)
+	}
+	control := &controlServer{server: s}
+	if _, err := control.ScheduleImpulse(context.Background(), &pb.Impulse{Dvx: 1}); err == nil {
+		t.Fatalf("expected an impulse without a body to be rejected")
+	}
+	for _, impulse := range []*pb.Impulse{{Name: "A", Dvy: 2, Sequence: 2}, {Name: "B", Dvx: 1, Time: 0.5}, {Name: "C", Dvx: 1}} {
+		if _, err := control.ScheduleImpulse(context.Background(), impulse); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A is massless so that B moves in a straight line
+	streams[0].send(t, &pb.CelestialBody{Sequence: 1, Name: "A", X: 10})
+	if data := streams[0].receive(t); len(data.Impulses) != 0 {
+		t.Fatalf("no impulse is due at round 0, got %v", data.Impulses)
+	}
+	streams[0].send(t, &pb.CelestialBody{Sequence: 2, Name: "A", X: 10})
+	data := streams[0].receive(t)
+	if len(data.Impulses) != 2 || data.Content["B"].Vx != 1 {
+		t.Fatalf("expected the impulses of A and B in the broadcast of round 1, got %v", data)
+	}
+	streams[0].send(t, &pb.CelestialBody{Sequence: 3, Name: "A", X: 10})
+	data = streams[0].receive(t)
+	if b := data.Content["B"]; len(data.Impulses) != 0 || b.Vx != 2 || math.Abs(b.X-1.5) > 1e-12 {
+		t.Fatalf("expected B to move at speed 2 after the impulse, got %v", b)
+	}
+	if reply, _ := control.Pause(context.Background(), &pb.ControlRequest{}); reply.Impulses != 1 {
+		t.Fatalf("expected the impulse of C to wait for it, got %v", reply)
+	}
+}
@@ -0,0 +1,71 @@
+// Package units describes the unit systems a simulation can run in, with the value of
+// the gravitational constant and of the speed of light in each of them.
+package units
+
+import (
+	"fmt"
+	"strings"
+
+	pb "taiyoukei/proto"
+)
+
+type System struct {
+	Units  pb.Units
+	Name   string
+	G      float64 // Gravitational constant
+	C      float64 // Speed of light
+	Length string
+	Mass   string
+	Time   string
+}
+
+var systems = map[pb.Units]System{
+	// The README examples use AU and solar masses, which makes the time unit a year / 2π
+	pb.Units_CANONICAL: {
+		Units:  pb.Units_CANONICAL,
+		Name:   "canonical",
+		G:      1,
+		C:      10065.32,
+		Length: "length unit",
+		Mass:   "mass unit",
+		Time:   "time unit",
+	},
+	pb.Units_SI: {
+		Units:  pb.Units_SI,
+		Name:   "si",
+		G:      6.67430e-11,
+		C:      299792458,
+		Length: "m",
+		Mass:   "kg",
+		Time:   "s",
+	},
+	// G is the square of the Gaussian gravitational constant
+	pb.Units_ASTRONOMICAL: {
+		Units:  pb.Units_ASTRONOMICAL,
+		Name:   "astronomical",
+		G:      0.01720209895 * 0.01720209895,
+		C:      173.1446326846693,
+		Length: "AU",
+		Mass:   "solar mass",
+		Time:   "day",
+	},
+}
+
+// Of returns the description of a unit system of the protocol
+func Of(units pb.Units) System {
+	system, ok := systems[units]
+	if !ok {
+		return systems[pb.Units_CANONICAL]
+	}
+	return system
+}
+
+// Parse looks a unit system up by its name: canonical, si or astronomical
+func Parse(name string) (System, error) {
+	for _, system := range systems {
+		if strings.EqualFold(system.Name, name) {
+			return system, nil
+		}
+	}
+	return System{}, fmt.Errorf("unknown unit system %q", name)
+}
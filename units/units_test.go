@@ -0,0 +1,39 @@
+package units
+
+import (
+	"testing"
+
+	pb "taiyoukei/proto"
+)
+
+func TestConstantsOfEverySystem(t *testing.T) {
+	for _, expected := range []struct {
+		name string
+		G    float64
+		C    float64
+	}{
+		{"canonical", 1, 10065.32},
+		{"si", 6.67430e-11, 299792458},
+		{"astronomical", 2.959122082855911e-4, 173.1446326846693},
+	} {
+		system, err := Parse(expected.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if system.G != expected.G || system.C != expected.C {
+			t.Errorf("%v: expected G = %v and c = %v, got %v and %v", expected.name, expected.G, expected.C, system.G, system.C)
+		}
+		if Of(system.Units) != system {
+			t.Errorf("%v: the system of the protocol differs from the parsed one", expected.name)
+		}
+	}
+}
+
+func TestUnknownSystems(t *testing.T) {
+	if _, err := Parse("cgs"); err == nil {
+		t.Fatalf("expected an unknown name to be rejected")
+	}
+	if system := Of(pb.Units(42)); system.Name != "canonical" {
+		t.Fatalf("expected an unknown system of the protocol to fall back to canonical units, got %v", system.Name)
+	}
+}